@@ -0,0 +1,439 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/ceph/go-ceph/rgw/admin"
+)
+
+// adminError is the error document returned by the RGW Admin Ops API, as JSON, or by
+// the S3 API, as XML.
+type adminError struct {
+	Code      string `json:"Code,omitempty" xml:"Code"`
+	RequestID string `json:"RequestId,omitempty" xml:"RequestId"`
+	HostID    string `json:"HostId,omitempty" xml:"HostId"`
+}
+
+func (e adminError) Error() string { return fmt.Sprintf("%s %s %s", e.Code, e.RequestID, e.HostID) }
+
+// Is allows comparing against the error reasons exported by go-ceph, e.g. admin.ErrNoSuchBucket.
+func (e adminError) Is(target error) bool { return target.Error() == e.Code }
+
+// requestAccessKey returns the access key a request is signed with, taken from the
+// credential of the authorization header or the query of presigned requests.
+func requestAccessKey(req *http.Request) string {
+	credential := req.URL.Query().Get("X-Amz-Credential")
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		if _, rest, ok := strings.Cut(auth, "Credential="); ok {
+			credential = rest
+		}
+	}
+	accessKey, _, _ := strings.Cut(credential, "/")
+	return accessKey
+}
+
+// sessionTokenTransport adds the session token of temporary provider credentials to
+// requests signed with the provider access key. go-ceph signs admin requests without
+// a session token, the token header is added after signing then, which RGW accepts as
+// it looks up the token independent of the signed headers. Requests signed with other
+// keys, e.g. of bucket owners, and anonymous requests are sent unchanged.
+type sessionTokenTransport struct {
+	next         http.RoundTripper
+	accessKey    string
+	sessionToken string
+}
+
+func newSessionTokenTransport(next http.RoundTripper, accessKey, sessionToken string) *sessionTokenTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &sessionTokenTransport{
+		next:         next,
+		accessKey:    accessKey,
+		sessionToken: sessionToken,
+	}
+}
+
+func (t *sessionTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("X-Amz-Security-Token") != "" || req.URL.Query().Has("X-Amz-Security-Token") || requestAccessKey(req) != t.accessKey {
+		return t.next.RoundTrip(req)
+	}
+
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Amz-Security-Token", t.sessionToken)
+	return t.next.RoundTrip(req)
+}
+
+// adminCall sends a signed request to the RGW Admin Ops API. It is used for
+// endpoints and parameters which are not (yet) supported by go-ceph. Responses are
+// not cached: the gateway sends no ETag or Last-Modified for admin responses and
+// ignores If-None-Match, so conditional requests would always transfer the payload.
+func adminCall(ctx context.Context, api *admin.API, method, path string, args url.Values) ([]byte, error) {
+	if args == nil {
+		args = url.Values{}
+	}
+	args.Set("format", "json")
+	return signedCall(ctx, api, method, "/admin"+path, args)
+}
+
+// signedCall sends a request signed with the credentials of the admin client to any
+// path of the gateway, e.g. for RGW extensions of the S3 API which the SDK does not
+// support. Errors of the admin api are decoded, others are returned with the body.
+func signedCall(ctx context.Context, api *admin.API, method, path string, args url.Values) ([]byte, error) {
+	// the path might already carry a query marker, e.g. "/user?quota"
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	if len(args) == 0 {
+		sep = ""
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s%s%s", api.Endpoint, path, sep, args.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the path is already escaped, e.g. bucket names with spaces. Like S3, the gateway
+	// signs it as sent, the signer would escape it a second time otherwise.
+	signer := v4.NewSigner(credentials.NewStaticCredentials(api.AccessKey, api.SecretKey, ""), func(s *v4.Signer) {
+		s.DisableURIPathEscaping = true
+	})
+	if _, err := signer.Sign(request, nil, "s3", "default", time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := api.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		var e adminError
+		if err := json.Unmarshal(body, &e); err != nil {
+			if xml.Unmarshal(body, &e) != nil || e.Code == "" {
+				return nil, fmt.Errorf("unexpected status %d from admin api: %s", resp.StatusCode, string(body))
+			}
+		}
+		return nil, e
+	}
+
+	return body, nil
+}
+
+// rgwBucketInfo extends admin.Bucket with fields go-ceph does not decode.
+type rgwBucketInfo struct {
+	admin.Bucket
+	CreationTime string `json:"creation_time"`
+}
+
+// getBucketInfo fetches bucket information including usage statistics.
+func getBucketInfo(ctx context.Context, api *admin.API, bucket string) (rgwBucketInfo, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/bucket", url.Values{
+		"bucket": {bucket},
+		"stats":  {"true"},
+	})
+	if err != nil {
+		return rgwBucketInfo{}, err
+	}
+
+	var info rgwBucketInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return rgwBucketInfo{}, fmt.Errorf("could not decode bucket info: %w", err)
+	}
+
+	return info, nil
+}
+
+// rgwUsageStats is the usage summary of a user as returned with the user info.
+type rgwUsageStats struct {
+	Size       uint64 `json:"size"`
+	SizeActual uint64 `json:"size_actual"`
+	NumObjects uint64 `json:"num_objects"`
+}
+
+// getUserStats fetches the usage statistics of a user.
+func getUserStats(ctx context.Context, api *admin.API, uid string) (rgwUsageStats, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/user", url.Values{
+		"uid":   {uid},
+		"stats": {"true"},
+	})
+	if err != nil {
+		return rgwUsageStats{}, err
+	}
+
+	var user struct {
+		Stats rgwUsageStats `json:"stats"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return rgwUsageStats{}, fmt.Errorf("could not decode user info: %w", err)
+	}
+
+	return user.Stats, nil
+}
+
+// getUserBucketStats fetches the usage statistics of all buckets owned by a user.
+func getUserBucketStats(ctx context.Context, api *admin.API, uid string) ([]rgwBucketInfo, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/bucket", url.Values{
+		"uid":   {uid},
+		"stats": {"true"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []rgwBucketInfo
+	if err := json.Unmarshal(body, &buckets); err != nil {
+		return nil, fmt.Errorf("could not decode bucket stats: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// bucketUsageStats returns the usage summary of a bucket.
+func bucketUsageStats(bucket admin.Bucket) rgwUsageStats {
+	return rgwUsageStats{
+		Size:       valueOrDefault(bucket.Usage.RgwMain.Size, 0),
+		SizeActual: valueOrDefault(bucket.Usage.RgwMain.SizeActual, 0),
+		NumObjects: valueOrDefault(bucket.Usage.RgwMain.NumObjects, 0),
+	}
+}
+
+// listMetadataKeys lists all keys of a metadata section, e.g. `bucket.instance`.
+func listMetadataKeys(ctx context.Context, api *admin.API, section string) ([]string, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/metadata/"+section, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	if err := json.Unmarshal(body, &keys); err != nil {
+		return nil, fmt.Errorf("could not decode %s metadata keys: %w", section, err)
+	}
+
+	return keys, nil
+}
+
+// findBucketInstance returns the bucket name (`[tenant/]bucket`) of a bucket instance id.
+// The instance might be a stale instance left behind by a reshard.
+func findBucketInstance(ctx context.Context, api *admin.API, bucketID string) (string, error) {
+	keys, err := listMetadataKeys(ctx, api, "bucket.instance")
+	if err != nil {
+		return "", err
+	}
+
+	// instance keys have the form "[tenant/]bucket:bucket_id"
+	for _, key := range keys {
+		if bucket, id, ok := strings.Cut(key, ":"); ok && id == bucketID {
+			return bucket, nil
+		}
+	}
+
+	return "", admin.ErrNoSuchBucket
+}
+
+// rgwPlacementTarget describes a placement target of a zonegroup.
+type rgwPlacementTarget struct {
+	Name           string   `json:"name"`
+	StorageClasses []string `json:"storage_classes"`
+}
+
+// rgwZonegroup describes the parts of a zonegroup used by the provider.
+type rgwZonegroup struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	DefaultPlacement string `json:"default_placement"`
+	PlacementTargets []struct {
+		Key string             `json:"key"`
+		Val rgwPlacementTarget `json:"val"`
+	} `json:"placement_targets"`
+}
+
+// rgwZonegroupMap is the zonegroup map as returned by the admin config endpoint.
+type rgwZonegroupMap struct {
+	Zonegroups []struct {
+		Key string       `json:"key"`
+		Val rgwZonegroup `json:"val"`
+	} `json:"zonegroups"`
+	MasterZonegroup string `json:"master_zonegroup"`
+}
+
+// getZonegroupMap fetches the zonegroup map of the gateway.
+func getZonegroupMap(ctx context.Context, api *admin.API) (rgwZonegroupMap, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/config", url.Values{
+		"type": {"zonegroup-map"},
+	})
+	if err != nil {
+		return rgwZonegroupMap{}, err
+	}
+
+	var zonegroupMap rgwZonegroupMap
+	if err := json.Unmarshal(body, &zonegroupMap); err != nil {
+		return rgwZonegroupMap{}, fmt.Errorf("could not decode zonegroup map: %w", err)
+	}
+
+	return zonegroupMap, nil
+}
+
+// defaultPlacement returns the default placement target of the master zonegroup.
+func (m rgwZonegroupMap) defaultPlacement() string {
+	for _, zg := range m.Zonegroups {
+		if zg.Key == m.MasterZonegroup {
+			return zg.Val.DefaultPlacement
+		}
+	}
+	return ""
+}
+
+// placementTarget returns the placement target with the given name in any zonegroup.
+func (m rgwZonegroupMap) placementTarget(name string) (rgwPlacementTarget, bool) {
+	for _, zg := range m.Zonegroups {
+		for _, pt := range zg.Val.PlacementTargets {
+			if pt.Key == name {
+				return pt.Val, true
+			}
+		}
+	}
+	return rgwPlacementTarget{}, false
+}
+
+// placementTargetNames returns the distinct names of the placement targets of all zonegroups, sorted.
+func (m rgwZonegroupMap) placementTargetNames() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, zg := range m.Zonegroups {
+		for _, pt := range zg.Val.PlacementTargets {
+			if !seen[pt.Key] {
+				seen[pt.Key] = true
+				names = append(names, pt.Key)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rgwZonePlacementPool describes the pools of a placement target in a zone.
+type rgwZonePlacementPool struct {
+	IndexPool      string `json:"index_pool"`
+	DataExtraPool  string `json:"data_extra_pool"`
+	StorageClasses map[string]struct {
+		DataPool        string `json:"data_pool"`
+		CompressionType string `json:"compression_type"`
+	} `json:"storage_classes"`
+}
+
+// rgwZone describes the parts of the zone configuration used by the provider.
+type rgwZone struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	PlacementPools []struct {
+		Key string               `json:"key"`
+		Val rgwZonePlacementPool `json:"val"`
+	} `json:"placement_pools"`
+}
+
+// getZone fetches the configuration of the zone the gateway is serving.
+func getZone(ctx context.Context, api *admin.API) (rgwZone, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/config", url.Values{
+		"type": {"zone"},
+	})
+	if err != nil {
+		return rgwZone{}, err
+	}
+
+	var zone rgwZone
+	if err := json.Unmarshal(body, &zone); err != nil {
+		return rgwZone{}, fmt.Errorf("could not decode zone: %w", err)
+	}
+
+	return zone, nil
+}
+
+// compressionType returns the compression configured for a storage class of a placement target.
+func (z rgwZone) compressionType(placement, storageClass string) (string, bool) {
+	for _, pp := range z.PlacementPools {
+		if pp.Key != placement {
+			continue
+		}
+		sc, ok := pp.Val.StorageClasses[storageClass]
+		if !ok {
+			return "", false
+		}
+		return sc.CompressionType, true
+	}
+	return "", false
+}
+
+// linkBucket links a bucket to a user like admin.API.LinkBucket, optionally
+// renaming the bucket as part of the link operation.
+func linkBucket(ctx context.Context, api *admin.API, link admin.BucketLinkInput, newBucketName string) error {
+	if newBucketName == "" || newBucketName == link.Bucket {
+		return api.LinkBucket(ctx, link)
+	}
+
+	args := url.Values{
+		"bucket":          {link.Bucket},
+		"uid":             {link.UID},
+		"new-bucket-name": {newBucketName},
+	}
+	if link.BucketID != "" {
+		args.Set("bucket-id", link.BucketID)
+	}
+
+	_, err := adminCall(ctx, api, http.MethodPut, "/bucket", args)
+	return err
+}
+
+// getLogShards returns the number of shards of the metadata or data log.
+func getLogShards(ctx context.Context, api *admin.API, logType string) (int, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/log", url.Values{
+		"type": {logType},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var info struct {
+		NumObjects int `json:"num_objects"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return 0, fmt.Errorf("could not decode %s log info: %w", logType, err)
+	}
+
+	return info.NumObjects, nil
+}
+
+// trimLog trims entries of a multisite log. The marker parameters are passed
+// with both their current and their legacy names, as the gateway versions differ.
+func trimLog(ctx context.Context, api *admin.API, logType string, args url.Values, startMarker, endMarker string) error {
+	args.Set("type", logType)
+	if startMarker != "" {
+		args.Set("start-marker", startMarker)
+	}
+	if endMarker != "" {
+		args.Set("end-marker", endMarker)
+		args.Set("marker", endMarker)
+	}
+
+	_, err := adminCall(ctx, api, http.MethodDelete, "/log", args)
+	return err
+}
@@ -0,0 +1,316 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ceph/go-ceph/rgw/admin"
+)
+
+const (
+	testAccessKey = "TESTACCESSKEY"
+	testSecretKey = "testsecretkey"
+)
+
+// testNames are uids, bucket names and object keys which are not safe in urls as is.
+var testNames = []string{
+	"legacy bucket",
+	"a+b",
+	"bücket-ünicode",
+	"100% done",
+	"tilde~and&amp",
+}
+
+// testRequest is a request received by testGateway, decoded like RGW decodes it.
+type testRequest struct {
+	Method string
+	// Path is the decoded path, "+" is not decoded to a space in paths
+	Path string
+	// Query is the decoded query
+	Query url.Values
+	// CanonicalURI and CanonicalQuery are the parts of the canonical request RGW computes
+	CanonicalURI   string
+	CanonicalQuery string
+}
+
+// testGateway is a fake RGW which verifies AWS signature v4 the way RGW does: the
+// canonical uri is the path exactly as sent, the canonical query is built from the
+// query parameters as sent, decoded without treating "+" as a space and encoded again.
+type testGateway struct {
+	*httptest.Server
+	t *testing.T
+
+	mu       sync.Mutex
+	requests []testRequest
+}
+
+func newTestGateway(t *testing.T) *testGateway {
+	g := &testGateway{t: t}
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serve))
+	t.Cleanup(g.Close)
+	return g
+}
+
+// last returns the last request received by the gateway.
+func (g *testGateway) last() testRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.requests) == 0 {
+		g.t.Fatal("the gateway received no request")
+	}
+	return g.requests[len(g.requests)-1]
+}
+
+func (g *testGateway) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rawPath, _, _ := strings.Cut(r.RequestURI, "?")
+	decodedPath, err := url.PathUnescape(rawPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := testRequest{
+		Method:         r.Method,
+		Path:           decodedPath,
+		Query:          query,
+		CanonicalURI:   rawPath,
+		CanonicalQuery: rgwCanonicalQuery(r.URL.RawQuery),
+	}
+	g.mu.Lock()
+	g.requests = append(g.requests, req)
+	g.mu.Unlock()
+
+	if expected, signature := rgwSignature(r, req, body), requestSignature(r); signature != expected {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `{"Code":"SignatureDoesNotMatch","RequestId":"tx0"}`)
+		return
+	}
+
+	switch {
+	case decodedPath == "/admin/user":
+		_ = json.NewEncoder(w).Encode(map[string]string{"user_id": query.Get("uid")})
+	case decodedPath == "/admin/bucket":
+		_ = json.NewEncoder(w).Encode(map[string]string{"bucket": query.Get("bucket"), "id": "id"})
+	case r.Method == http.MethodPut:
+		sum := md5.Sum(body)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// rgwURIEncode encodes s like RGW (and AWS) do for the canonical request.
+func rgwURIEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// rgwCanonicalQuery builds the canonical query from the raw query as RGW does.
+func rgwCanonicalQuery(rawQuery string) string {
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(param, "=")
+		key, _ = url.PathUnescape(key)
+		value, _ = url.PathUnescape(value)
+		params = append(params, rgwURIEncode(key)+"="+rgwURIEncode(value))
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// requestSignature returns the signature of the authorization header of r.
+func requestSignature(r *http.Request) string {
+	_, signature, _ := strings.Cut(r.Header.Get("Authorization"), "Signature=")
+	return signature
+}
+
+// rgwSignature computes the signature RGW expects for r.
+func rgwSignature(r *http.Request, req testRequest, body []byte) string {
+	auth := r.Header.Get("Authorization")
+	_, credential, _ := strings.Cut(auth, "Credential=")
+	credential, _, _ = strings.Cut(credential, ",")
+	_, scope, _ := strings.Cut(credential, "/")
+	_, signedHeaders, _ := strings.Cut(auth, "SignedHeaders=")
+	signedHeaders, _, _ = strings.Cut(signedHeaders, ",")
+
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		fmt.Fprintf(&headers, "%s:%s\n", name, strings.TrimSpace(value))
+	}
+
+	payload := r.Header.Get("X-Amz-Content-Sha256")
+	if payload == "" {
+		sum := sha256.Sum256(body)
+		payload = hex.EncodeToString(sum[:])
+	}
+
+	canonical := strings.Join([]string{r.Method, req.CanonicalURI, req.CanonicalQuery, headers.String(), signedHeaders, payload}, "\n")
+	canonicalSum := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", r.Header.Get("X-Amz-Date"), scope, hex.EncodeToString(canonicalSum[:])}, "\n")
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func newTestAdmin(t *testing.T, g *testGateway) *admin.API {
+	api, err := admin.New(g.URL, testAccessKey, testSecretKey, g.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
+
+func TestGoCephEncoding(t *testing.T) {
+	g := newTestGateway(t)
+	api := newTestAdmin(t, g)
+
+	for _, name := range testNames {
+		user, err := api.GetUser(context.Background(), admin.User{ID: name})
+		if err != nil {
+			t.Errorf("GetUser(%q): %s", name, err)
+			continue
+		}
+		if user.ID != name {
+			t.Errorf("GetUser(%q): gateway decoded uid %q", name, user.ID)
+		}
+
+		bucket, err := api.GetBucketInfo(context.Background(), admin.Bucket{Bucket: name})
+		if err != nil {
+			t.Errorf("GetBucketInfo(%q): %s", name, err)
+			continue
+		}
+		if bucket.Bucket != name {
+			t.Errorf("GetBucketInfo(%q): gateway decoded bucket %q", name, bucket.Bucket)
+		}
+	}
+}
+
+func TestAdminCallEncoding(t *testing.T) {
+	g := newTestGateway(t)
+	api := newTestAdmin(t, g)
+
+	for _, name := range testNames {
+		bucket, err := getBucketInfo(context.Background(), api, name)
+		if err != nil {
+			t.Errorf("getBucketInfo(%q): %s", name, err)
+			continue
+		}
+		if bucket.Bucket.Bucket != name {
+			t.Errorf("getBucketInfo(%q): gateway decoded bucket %q", name, bucket.Bucket.Bucket)
+		}
+		if got, expected := g.last().CanonicalQuery, "bucket="+rgwURIEncode(name)+"&format=json&stats=true"; got != expected {
+			t.Errorf("getBucketInfo(%q): canonical query %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestSignedCallEncoding(t *testing.T) {
+	g := newTestGateway(t)
+	api := newTestAdmin(t, g)
+
+	for _, name := range testNames {
+		_, err := signedCall(context.Background(), api, http.MethodDelete, "/"+url.PathEscape(name), url.Values{"notification": {name}})
+		if err != nil {
+			t.Errorf("signedCall(%q): %s", name, err)
+			continue
+		}
+		req := g.last()
+		if req.Path != "/"+name {
+			t.Errorf("signedCall(%q): gateway decoded path %q", name, req.Path)
+		}
+		if req.Query.Get("notification") != name {
+			t.Errorf("signedCall(%q): gateway decoded notification %q", name, req.Query.Get("notification"))
+		}
+	}
+}
+
+func TestS3Encoding(t *testing.T) {
+	g := newTestGateway(t)
+	client := s3.New(s3.Options{
+		Credentials:      aws.NewCredentialsCache(credentialsProvider{}),
+		EndpointResolver: s3.EndpointResolverFromURL(g.URL),
+		UsePathStyle:     true,
+		HTTPClient:       g.Client(),
+		Region:           "default",
+	})
+
+	for _, name := range testNames {
+		key := "dir/" + name + "/" + name
+		_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket: aws.String(name),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader([]byte(name)),
+		})
+		if err != nil {
+			t.Errorf("PutObject(%q, %q): %s", name, key, err)
+			continue
+		}
+		if req := g.last(); req.Path != "/"+name+"/"+key {
+			t.Errorf("PutObject(%q, %q): gateway decoded path %q", name, key, req.Path)
+		}
+
+		_, err = client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: aws.String(name),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			t.Errorf("DeleteObject(%q, %q): %s", name, key, err)
+		}
+	}
+}
+
+// credentialsProvider provides the credentials of the test gateway.
+type credentialsProvider struct{}
+
+func (credentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: testAccessKey, SecretAccessKey: testSecretKey}, nil
+}
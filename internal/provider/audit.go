@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// auditRecord is a single mutating request written to the audit log.
+type auditRecord struct {
+	Time      string `json:"time"`
+	Identity  string `json:"identity"`
+	Operation string `json:"operation"`
+	Target    string `json:"target"`
+	Status    int    `json:"status,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// auditReadActions are the prefixes of actions of the query apis (iam, sts, sns) which
+// are sent as POST but do not modify anything.
+var auditReadActions = []string{"Get", "List", "Describe", "AssumeRole"}
+
+// auditRedactedParams are query parameters whose values are never written to the audit log.
+var auditRedactedParams = []string{"secret-key", "secret"}
+
+// auditTransport records every mutating request of the admin, s3 and v1 sdk clients in a
+// local JSONL file and/or as objects in a bucket. Requests are recorded after the gateway
+// answered, including the status, so failed changes are audited as well. A record which
+// can not be written is logged as a warning and does not fail the request, as the change
+// already happened on the gateway.
+type auditTransport struct {
+	next http.RoundTripper
+
+	// path is the local JSONL file, empty if not configured
+	path string
+	mu   sync.Mutex
+
+	// s3 writes the records to bucket, nil if not configured. It must not use this
+	// transport itself, otherwise every record would be audited again.
+	s3     *s3.Client
+	bucket string
+}
+
+func newAuditTransport(next http.RoundTripper, path string, s3client *s3.Client, bucket string) *auditTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &auditTransport{
+		next:   next,
+		path:   path,
+		s3:     s3client,
+		bucket: bucket,
+	}
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return t.next.RoundTrip(req)
+	}
+
+	action, req, err := auditAction(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, prefix := range auditReadActions {
+		if strings.HasPrefix(action, prefix) {
+			return t.next.RoundTrip(req)
+		}
+	}
+
+	record := auditRecord{
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Identity:  requestAccessKey(req),
+		Operation: action,
+		Target:    auditTarget(req.URL),
+	}
+	if record.Operation == "" {
+		record.Operation = req.Method
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.Status = resp.StatusCode
+	}
+
+	if werr := t.write(req.Context(), record); werr != nil {
+		tflog.Warn(req.Context(), fmt.Sprintf("could not write audit record for %s %s: %s", record.Operation, record.Target, werr.Error()))
+	}
+	return resp, err
+}
+
+// write appends the record to the audit file and stores it in the audit bucket.
+func (t *auditTransport) write(ctx context.Context, record auditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if t.path != "" {
+		t.mu.Lock()
+		err := appendLine(t.path, line)
+		t.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.s3 != nil {
+		// one object per record, ordered by time, as objects can not be appended to
+		key := fmt.Sprintf("%s-%s.json", strings.ReplaceAll(record.Time, ":", ""), record.Identity)
+		_, err := t.s3.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(t.bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(line),
+			ContentType: aws.String("application/json"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendLine appends a line to the file, creating it if necessary.
+func appendLine(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// auditAction returns the action of a request to the query apis (iam, sts, sns), which is
+// sent in the form encoded body, or an empty string for other requests. As the body is
+// consumed, the request to send is returned too, a copy with the body restored.
+func auditAction(req *http.Request) (string, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return "", req, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", req, nil
+	}
+	return form.Get("Action"), req, nil
+}
+
+// auditTarget returns the path and query of a request without signature parameters and
+// with secrets redacted, e.g. "/admin/user?uid=foo" or "/bucket/key?tagging=".
+func auditTarget(u *url.URL) string {
+	query := u.Query()
+	for param := range query {
+		if strings.HasPrefix(strings.ToLower(param), "x-amz-") || param == "format" {
+			query.Del(param)
+		}
+	}
+	for _, param := range auditRedactedParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+
+	target := u.EscapedPath()
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	return target
+}
@@ -0,0 +1,481 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// corsMethods are the methods RGW accepts in CORS rules.
+var corsMethods = []string{"GET", "PUT", "POST", "DELETE", "HEAD"}
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &BucketCorsResource{}
+var _ resource.ResourceWithValidateConfig = &BucketCorsResource{}
+var _ resource.ResourceWithImportState = &BucketCorsResource{}
+
+func NewBucketCorsResource() resource.Resource {
+	return &BucketCorsResource{}
+}
+
+type BucketCorsResource struct {
+	client *RgwClient
+}
+
+type BucketCorsResourceModel struct {
+	Id     types.String          `tfsdk:"id"`
+	Bucket types.String          `tfsdk:"bucket"`
+	Rules  []BucketCorsRuleModel `tfsdk:"cors_rule"`
+}
+
+type BucketCorsRuleModel struct {
+	Id             types.String   `tfsdk:"id"`
+	AllowedMethods []types.String `tfsdk:"allowed_methods"`
+	AllowedOrigins []types.String `tfsdk:"allowed_origins"`
+	AllowedHeaders []types.String `tfsdk:"allowed_headers"`
+	ExposeHeaders  []types.String `tfsdk:"expose_headers"`
+	MaxAgeSeconds  types.Int64    `tfsdk:"max_age_seconds"`
+}
+
+func (r *BucketCorsResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_cors"
+}
+
+func (r *BucketCorsResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "CORS configuration of a bucket. Methods, origins and headers are validated at plan time, as RGW rejects the whole configuration with an unspecific error, e.g. for origins or headers with more than one wildcard. Methods, origins and headers are sets and rules are matched by their id when the configuration is read, so reordering does not show up in the plan. Upon deletion, the CORS configuration is removed. Existing configurations can be imported by the bucket name.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"cors_rule": schema.ListNestedAttribute{
+				MarkdownDescription: "CORS rules. RGW applies the first rule matching the origin and method of a request.",
+				Required:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							MarkdownDescription: "Unique id of the rule, rules are matched by their id",
+							Required:            true,
+						},
+						"allowed_methods": schema.SetAttribute{
+							MarkdownDescription: "Allowed methods - can be `GET`, `PUT`, `POST`, `DELETE` or `HEAD`",
+							ElementType:         types.StringType,
+							Required:            true,
+							Validators: []validator.Set{
+								setvalidator.SizeAtLeast(1),
+								setvalidator.ValueStringsAre(stringvalidator.OneOf(corsMethods...)),
+							},
+						},
+						"allowed_origins": schema.SetAttribute{
+							MarkdownDescription: "Allowed origins as `scheme://host[:port]`, e.g. `https://*.example.com`, or `*` for all origins. Each origin may contain at most one wildcard.",
+							ElementType:         types.StringType,
+							Required:            true,
+							Validators: []validator.Set{
+								setvalidator.SizeAtLeast(1),
+							},
+						},
+						"allowed_headers": schema.SetAttribute{
+							MarkdownDescription: "Headers allowed in preflight requests, e.g. `x-amz-*`. Each header may contain at most one wildcard.",
+							ElementType:         types.StringType,
+							Optional:            true,
+						},
+						"expose_headers": schema.SetAttribute{
+							MarkdownDescription: "Response headers accessible to the client, e.g. `ETag`",
+							ElementType:         types.StringType,
+							Optional:            true,
+						},
+						"max_age_seconds": schema.Int64Attribute{
+							MarkdownDescription: "Time in seconds browsers may cache the preflight response",
+							Optional:            true,
+							Validators: []validator.Int64{
+								int64validator.AtLeast(1),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// validateCorsOrigin checks the syntax of an allowed origin as enforced by RGW.
+func validateCorsOrigin(origin string) error {
+	if strings.Count(origin, "*") > 1 {
+		return errors.New("RGW rejects origins with more than one wildcard")
+	}
+	if origin == "*" {
+		return nil
+	}
+	if strings.ContainsAny(origin, " \t") {
+		return errors.New("origins must not contain whitespace")
+	}
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || scheme == "" || host == "" {
+		return errors.New("origins must have the form scheme://host[:port] or be *")
+	}
+	if strings.Contains(host, "/") {
+		return errors.New("origins must not contain a path")
+	}
+	return nil
+}
+
+// validateCorsHeader checks an allowed or exposed header as enforced by RGW.
+func validateCorsHeader(header string, wildcard bool) error {
+	if header == "" || strings.ContainsAny(header, " \t:") {
+		return errors.New("headers must be non-empty header names")
+	}
+	if !wildcard && strings.Contains(header, "*") {
+		return errors.New("exposed headers must not contain wildcards")
+	}
+	if strings.Count(header, "*") > 1 {
+		return errors.New("RGW rejects headers with more than one wildcard")
+	}
+	return nil
+}
+
+// corsMethodsOverlap returns the methods allowed by both rules.
+func corsMethodsOverlap(a, b BucketCorsRuleModel) []string {
+	var common []string
+	for _, ma := range a.AllowedMethods {
+		for _, mb := range b.AllowedMethods {
+			if !ma.IsUnknown() && ma.Equal(mb) {
+				common = append(common, ma.ValueString())
+			}
+		}
+	}
+	return common
+}
+
+func (r *BucketCorsResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	// rules are only validated once they are known
+	var rules types.List
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("cors_rule"), &rules)...)
+	if resp.Diagnostics.HasError() || rules.IsUnknown() {
+		return
+	}
+
+	var data BucketCorsResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ids := make(map[string]bool)
+	for i, rule := range data.Rules {
+		rulePath := path.Root("cors_rule").AtListIndex(i)
+		if !rule.Id.IsUnknown() {
+			if ids[rule.Id.ValueString()] {
+				resp.Diagnostics.AddAttributeError(rulePath.AtName("id"), "duplicate cors rule id", fmt.Sprintf("cors rule id '%s' is used more than once", rule.Id.ValueString()))
+			}
+			ids[rule.Id.ValueString()] = true
+		}
+
+		for _, o := range rule.AllowedOrigins {
+			if o.IsUnknown() {
+				continue
+			}
+			if err := validateCorsOrigin(o.ValueString()); err != nil {
+				resp.Diagnostics.AddAttributeError(rulePath.AtName("allowed_origins"), "invalid cors origin", fmt.Sprintf("origin '%s' is invalid: %s", o.ValueString(), err.Error()))
+			}
+		}
+		for _, h := range rule.AllowedHeaders {
+			if h.IsUnknown() {
+				continue
+			}
+			if err := validateCorsHeader(h.ValueString(), true); err != nil {
+				resp.Diagnostics.AddAttributeError(rulePath.AtName("allowed_headers"), "invalid cors header", fmt.Sprintf("header '%s' is invalid: %s", h.ValueString(), err.Error()))
+			}
+		}
+		for _, h := range rule.ExposeHeaders {
+			if h.IsUnknown() {
+				continue
+			}
+			if err := validateCorsHeader(h.ValueString(), false); err != nil {
+				resp.Diagnostics.AddAttributeError(rulePath.AtName("expose_headers"), "invalid cors header", fmt.Sprintf("header '%s' is invalid: %s", h.ValueString(), err.Error()))
+			}
+		}
+	}
+
+	// RGW applies the first matching rule, so rules after a rule allowing all origins
+	// are never applied for the methods of that rule
+	for i, a := range data.Rules {
+		if !containsStringValue(a.AllowedOrigins, "*") {
+			continue
+		}
+		for j := i + 1; j < len(data.Rules); j++ {
+			b := data.Rules[j]
+			if common := corsMethodsOverlap(a, b); len(common) > 0 {
+				resp.Diagnostics.AddAttributeWarning(path.Root("cors_rule").AtListIndex(j), "shadowed cors rule", fmt.Sprintf("cors rule '%s' is never applied for methods %s, as the preceding rule '%s' allows all origins", b.Id.ValueString(), strings.Join(common, ", "), a.Id.ValueString()))
+			}
+		}
+	}
+}
+
+// containsStringValue reports whether the list contains the known value s.
+func containsStringValue(list []types.String, s string) bool {
+	for _, v := range list {
+		if !v.IsUnknown() && v.ValueString() == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *BucketCorsResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_cors")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.client = client
+}
+
+func (r *BucketCorsResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// the cors configuration is imported by the bucket name
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("bucket"), req.ID)...)
+}
+
+// isNoSuchCORSConfiguration reports whether err signals that a bucket has no cors configuration.
+func isNoSuchCORSConfiguration(err error) bool {
+	var ae smithy.APIError
+	return errors.As(err, &ae) && ae.ErrorCode() == "NoSuchCORSConfiguration"
+}
+
+// stringValues returns the values of a set or list of strings.
+func stringValues(list []types.String) []string {
+	values := make([]string, len(list))
+	for i, v := range list {
+		values[i] = v.ValueString()
+	}
+	return values
+}
+
+// stringValuesOrNull returns the values as a set or list of strings, nil for an empty list.
+func stringValuesOrNull(values []string) []types.String {
+	if len(values) == 0 {
+		return nil
+	}
+	list := make([]types.String, len(values))
+	for i, v := range values {
+		list[i] = types.StringValue(v)
+	}
+	return list
+}
+
+// putCors replaces the cors configuration of the bucket.
+func (r *BucketCorsResource) putCors(ctx context.Context, data *BucketCorsResourceModel) error {
+	config := &s3types.CORSConfiguration{}
+	for _, rule := range data.Rules {
+		cr := s3types.CORSRule{
+			ID:             aws.String(rule.Id.ValueString()),
+			AllowedMethods: stringValues(rule.AllowedMethods),
+			AllowedOrigins: stringValues(rule.AllowedOrigins),
+			AllowedHeaders: stringValues(rule.AllowedHeaders),
+			ExposeHeaders:  stringValues(rule.ExposeHeaders),
+		}
+		if !rule.MaxAgeSeconds.IsNull() {
+			cr.MaxAgeSeconds = int32(rule.MaxAgeSeconds.ValueInt64())
+		}
+		config.CORSRules = append(config.CORSRules, cr)
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("put %d cors rules to bucket %s", len(config.CORSRules), data.Bucket.ValueString()))
+
+	_, err := r.client.S3.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+		Bucket:            aws.String(data.Bucket.ValueString()),
+		CORSConfiguration: config,
+	})
+	return err
+}
+
+// corsRuleFromS3 returns the resource attributes of a cors rule returned by the gateway.
+func corsRuleFromS3(cr s3types.CORSRule) BucketCorsRuleModel {
+	rule := BucketCorsRuleModel{
+		Id:             types.StringValue(aws.StringValue(cr.ID)),
+		AllowedMethods: stringValuesOrNull(cr.AllowedMethods),
+		AllowedOrigins: stringValuesOrNull(cr.AllowedOrigins),
+		AllowedHeaders: stringValuesOrNull(cr.AllowedHeaders),
+		ExposeHeaders:  stringValuesOrNull(cr.ExposeHeaders),
+		MaxAgeSeconds:  types.Int64Null(),
+	}
+	if cr.MaxAgeSeconds > 0 {
+		rule.MaxAgeSeconds = types.Int64Value(int64(cr.MaxAgeSeconds))
+	}
+	return rule
+}
+
+// orderCorsRules orders the rules returned by the gateway like the prior rules by id, so
+// the plan only shows the rules which actually changed. Rules unknown to the prior state,
+// e.g. on import, are appended in the order of the gateway.
+func orderCorsRules(rules []BucketCorsRuleModel, prior []BucketCorsRuleModel) []BucketCorsRuleModel {
+	byID := make(map[string]BucketCorsRuleModel, len(rules))
+	for _, rule := range rules {
+		byID[rule.Id.ValueString()] = rule
+	}
+
+	ordered := make([]BucketCorsRuleModel, 0, len(rules))
+	for _, p := range prior {
+		if rule, ok := byID[p.Id.ValueString()]; ok {
+			ordered = append(ordered, rule)
+			delete(byID, p.Id.ValueString())
+		}
+	}
+	for _, rule := range rules {
+		if _, ok := byID[rule.Id.ValueString()]; ok {
+			ordered = append(ordered, rule)
+		}
+	}
+	return ordered
+}
+
+func (r *BucketCorsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketCorsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := retryNoSuchBucket(ctx, func() error {
+		return r.putCors(ctx, data)
+	})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not put bucket cors", err)...)
+		return
+	}
+
+	// use bucket name as resource id
+	data.Id = types.StringValue(data.Bucket.ValueString())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketCorsResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *BucketCorsResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	out, err := r.client.S3.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+	})
+	if err != nil {
+		if isNoSuchBucket(err) || isNoSuchCORSConfiguration(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket cors", err)...)
+		return
+	}
+
+	rules := make([]BucketCorsRuleModel, len(out.CORSRules))
+	for i, cr := range out.CORSRules {
+		rules[i] = corsRuleFromS3(cr)
+	}
+	data.Rules = orderCorsRules(rules, data.Rules)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketCorsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketCorsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.putCors(ctx, data); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not put bucket cors", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketCorsResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *BucketCorsResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := r.client.S3.DeleteBucketCors(ctx, &s3.DeleteBucketCorsInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+	})
+	if err != nil && !isNoSuchBucket(err) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete bucket cors", err)...)
+		return
+	}
+}
@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &BucketDataSource{}
+
+func NewBucketDataSource() datasource.DataSource {
+	return &BucketDataSource{}
+}
+
+type BucketDataSource struct {
+	client *RgwClient
+}
+
+type BucketDataSourceModel struct {
+	Name         types.String `tfsdk:"name"`
+	Tenant       types.String `tfsdk:"tenant"`
+	BucketID     types.String `tfsdk:"bucket_id"`
+	CurrentID    types.String `tfsdk:"current_bucket_id"`
+	Owner        types.String `tfsdk:"owner"`
+	NumObjects   types.Int64  `tfsdk:"num_objects"`
+	SizeActual   types.Int64  `tfsdk:"size_actual"`
+	SizeUtilized types.Int64  `tfsdk:"size_utilized"`
+	NumShards    types.Int64  `tfsdk:"num_shards"`
+	Placement    types.String `tfsdk:"placement_rule"`
+	StorageClass types.String `tfsdk:"storage_class"`
+	Zonegroup    types.String `tfsdk:"zonegroup"`
+	IndexType    types.String `tfsdk:"index_type"`
+	CreationTime types.String `tfsdk:"creation_time"`
+}
+
+func (d *BucketDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket"
+}
+
+func (d *BucketDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Bucket in Ceph RGW, looked up by its name or by a bucket instance id. Looking up by instance id also finds buckets by the id of a stale instance left behind by a reshard, `current_bucket_id` then differs from `bucket_id`.",
+
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name. Exactly one of `name` and `bucket_id` must be set.",
+				Optional:            true,
+				Computed:            true,
+				Validators: []validator.String{
+					stringvalidator.ExactlyOneOf(path.MatchRoot("bucket_id")),
+				},
+			},
+			"tenant": schema.StringAttribute{
+				MarkdownDescription: "The tenant of the bucket. Computed when looking up by `bucket_id`.",
+				Optional:            true,
+				Computed:            true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("bucket_id")),
+				},
+			},
+			"bucket_id": schema.StringAttribute{
+				MarkdownDescription: "The bucket instance id to look up. Exactly one of `name` and `bucket_id` must be set.",
+				Optional:            true,
+				Computed:            true,
+			},
+			"current_bucket_id": schema.StringAttribute{
+				MarkdownDescription: "The id of the current bucket instance",
+				Computed:            true,
+			},
+			"owner": schema.StringAttribute{
+				MarkdownDescription: "The UID of the user owning the bucket",
+				Computed:            true,
+			},
+			"num_objects": schema.Int64Attribute{
+				MarkdownDescription: "Number of objects in the bucket",
+				Computed:            true,
+			},
+			"size_actual": schema.Int64Attribute{
+				MarkdownDescription: "Actual size of the bucket in bytes (including allocation overhead)",
+				Computed:            true,
+			},
+			"size_utilized": schema.Int64Attribute{
+				MarkdownDescription: "Utilized size of the bucket in bytes (after compression)",
+				Computed:            true,
+			},
+			"num_shards": schema.Int64Attribute{
+				MarkdownDescription: "Number of bucket index shards",
+				Computed:            true,
+			},
+			"placement_rule": schema.StringAttribute{
+				MarkdownDescription: "The placement target of the bucket",
+				Computed:            true,
+			},
+			"storage_class": schema.StringAttribute{
+				MarkdownDescription: "The default storage class of the bucket",
+				Computed:            true,
+			},
+			"zonegroup": schema.StringAttribute{
+				MarkdownDescription: "ID of the zonegroup the bucket is placed in",
+				Computed:            true,
+			},
+			"index_type": schema.StringAttribute{
+				MarkdownDescription: "Bucket index type, e.g. `Normal` or `Indexless`",
+				Computed:            true,
+			},
+			"creation_time": schema.StringAttribute{
+				MarkdownDescription: "Creation time of the bucket",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *BucketDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *BucketDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data BucketDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// resolve the bucket name of the instance
+	bucketName := tenantedBucket(data.Tenant.ValueString(), data.Name.ValueString())
+	if !data.BucketID.IsNull() {
+		var err error
+		bucketName, err = findBucketInstance(ctx, d.client.Admin, data.BucketID.ValueString())
+		if err != nil {
+			if errors.Is(err, admin.ErrNoSuchBucket) {
+				resp.Diagnostics.AddAttributeError(path.Root("bucket_id"), "bucket instance not found", fmt.Sprintf("no bucket instance with id %s", data.BucketID.ValueString()))
+				return
+			}
+			resp.Diagnostics.Append(errorDiagnostics(d.client, "could not look up bucket instance", err)...)
+			return
+		}
+	}
+
+	bucket, err := getBucketInfo(ctx, d.client.Admin, bucketName)
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(d.client, fmt.Sprintf("could not get bucket %s", bucketName), err)...)
+		return
+	}
+
+	// tenanted buckets are reported as "tenant/bucket"
+	tenant, name, found := strings.Cut(bucketName, "/")
+	if !found {
+		tenant, name = "", bucketName
+	}
+	data.Name = types.StringValue(name)
+	if tenant != "" {
+		data.Tenant = types.StringValue(tenant)
+	}
+	if data.BucketID.IsNull() {
+		data.BucketID = types.StringValue(bucket.ID)
+	}
+	data.CurrentID = types.StringValue(bucket.ID)
+
+	data.Owner = types.StringValue(bucket.Owner)
+	usage := bucket.Usage.RgwMain
+	data.NumObjects = types.Int64Value(int64(valueOrDefault(usage.NumObjects, 0)))
+	data.SizeActual = types.Int64Value(int64(valueOrDefault(usage.SizeActual, 0)))
+	data.SizeUtilized = types.Int64Value(int64(valueOrDefault(usage.SizeUtilized, 0)))
+	data.NumShards = types.Int64Value(int64(valueOrDefault(bucket.NumShards, 0)))
+	data.Zonegroup = types.StringValue(bucket.Zonegroup)
+	data.IndexType = types.StringValue(bucket.IndexType)
+	data.CreationTime = types.StringValue(bucket.CreationTime)
+
+	// placement rule is reported as "<placement>[/<storage class>]"
+	placement, storageClass, _ := strings.Cut(bucket.PlacementRule, "/")
+	if storageClass == "" {
+		storageClass = "STANDARD"
+	}
+	data.Placement = types.StringValue(placement)
+	data.StorageClass = types.StringValue(storageClass)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
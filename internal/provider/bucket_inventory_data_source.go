@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// inventoryColumns is the header row of the inventory file.
+var inventoryColumns = []string{"bucket", "key", "size", "last_modified", "etag", "storage_class"}
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &BucketInventoryDataSource{}
+
+func NewBucketInventoryDataSource() datasource.DataSource {
+	return &BucketInventoryDataSource{}
+}
+
+type BucketInventoryDataSource struct {
+	client *RgwClient
+}
+
+type BucketInventoryDataSourceModel struct {
+	Bucket       types.String `tfsdk:"bucket"`
+	Prefix       types.String `tfsdk:"prefix"`
+	PageSize     types.Int64  `tfsdk:"page_size"`
+	Filename     types.String `tfsdk:"filename"`
+	GeneratedAt  types.String `tfsdk:"generated_at"`
+	ObjectCount  types.Int64  `tfsdk:"object_count"`
+	TotalSize    types.Int64  `tfsdk:"total_size"`
+	OutputSHA256 types.String `tfsdk:"output_sha256"`
+}
+
+func (d *BucketInventoryDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_inventory"
+}
+
+func (d *BucketInventoryDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Snapshot of the objects in a bucket written to a local CSV file, similar to an S3 inventory report. RGW has no native inventory, so the bucket is listed on every refresh. The file starts with a header row of the columns `bucket`, `key`, `size`, `last_modified`, `etag` and `storage_class`.",
+
+		Attributes: map[string]schema.Attribute{
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+			},
+			"prefix": schema.StringAttribute{
+				MarkdownDescription: "Only include objects with keys starting with the prefix",
+				Optional:            true,
+			},
+			"page_size": schema.Int64Attribute{
+				MarkdownDescription: "Number of keys requested per list request. Defaults to `1000`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, defaultListPageSize),
+				},
+			},
+			"filename": schema.StringAttribute{
+				MarkdownDescription: "Path of the CSV file to write the snapshot to. An existing file is overwritten.",
+				Required:            true,
+			},
+			"generated_at": schema.StringAttribute{
+				MarkdownDescription: "Time the bucket was listed (RFC 3339)",
+				Computed:            true,
+			},
+			"object_count": schema.Int64Attribute{
+				MarkdownDescription: "Number of objects in the snapshot",
+				Computed:            true,
+			},
+			"total_size": schema.Int64Attribute{
+				MarkdownDescription: "Total size of the objects in the snapshot in bytes",
+				Computed:            true,
+			},
+			"output_sha256": schema.StringAttribute{
+				MarkdownDescription: "SHA256 checksum of the written file",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *BucketInventoryDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the data source can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_inventory")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.client = client
+}
+
+func (d *BucketInventoryDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data BucketInventoryDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	pageSize := int32(defaultListPageSize)
+	if !data.PageSize.IsNull() {
+		pageSize = int32(data.PageSize.ValueInt64())
+	}
+
+	generatedAt := time.Now().UTC()
+	objects, err := listObjects(ctx, d.client.S3, data.Bucket.ValueString(), data.Prefix.ValueString(), pageSize, 0, false)
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(d.client, "could not list objects", err)...)
+		return
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(inventoryColumns); err != nil {
+		resp.Diagnostics.AddError("could not encode inventory", err.Error())
+		return
+	}
+	var totalSize int64
+	for _, obj := range objects {
+		var lastModified string
+		if obj.LastModified != nil {
+			lastModified = obj.LastModified.UTC().Format(time.RFC3339)
+		}
+		err := w.Write([]string{
+			data.Bucket.ValueString(),
+			aws.StringValue(obj.Key),
+			strconv.FormatInt(obj.Size, 10),
+			lastModified,
+			strings.Trim(aws.StringValue(obj.ETag), `"`),
+			string(obj.StorageClass),
+		})
+		if err != nil {
+			resp.Diagnostics.AddError("could not encode inventory", err.Error())
+			return
+		}
+		totalSize += obj.Size
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		resp.Diagnostics.AddError("could not encode inventory", err.Error())
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("write inventory of %d objects in bucket %s to %s", len(objects), data.Bucket.ValueString(), data.Filename.ValueString()))
+
+	if err := os.WriteFile(data.Filename.ValueString(), buf.Bytes(), 0o644); err != nil {
+		resp.Diagnostics.AddError("could not write inventory file", err.Error())
+		return
+	}
+
+	checksum := sha256.Sum256(buf.Bytes())
+	data.GeneratedAt = types.StringValue(generatedAt.Format(time.RFC3339))
+	data.ObjectCount = types.Int64Value(int64(len(objects)))
+	data.TotalSize = types.Int64Value(totalSize)
+	data.OutputSHA256 = types.StringValue(hex.EncodeToString(checksum[:]))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,485 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &BucketLifecycleResource{}
+var _ resource.ResourceWithModifyPlan = &BucketLifecycleResource{}
+var _ resource.ResourceWithImportState = &BucketLifecycleResource{}
+
+func NewBucketLifecycleResource() resource.Resource {
+	return &BucketLifecycleResource{}
+}
+
+type BucketLifecycleResource struct {
+	client *RgwClient
+}
+
+type BucketLifecycleResourceModel struct {
+	Id     types.String               `tfsdk:"id"`
+	Bucket types.String               `tfsdk:"bucket"`
+	Rules  []BucketLifecycleRuleModel `tfsdk:"rule"`
+}
+
+type BucketLifecycleRuleModel struct {
+	Id                       types.String                     `tfsdk:"id"`
+	Enabled                  types.Bool                       `tfsdk:"enabled"`
+	Prefix                   types.String                     `tfsdk:"prefix"`
+	ExpirationDays           types.Int64                      `tfsdk:"expiration_days"`
+	NoncurrentExpirationDays types.Int64                      `tfsdk:"noncurrent_expiration_days"`
+	AbortMultipartDays       types.Int64                      `tfsdk:"abort_incomplete_multipart_upload_days"`
+	Transitions              []BucketLifecycleTransitionModel `tfsdk:"transition"`
+}
+
+type BucketLifecycleTransitionModel struct {
+	Days         types.Int64  `tfsdk:"days"`
+	StorageClass types.String `tfsdk:"storage_class"`
+}
+
+func (r *BucketLifecycleResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_lifecycle"
+}
+
+func (r *BucketLifecycleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	daysValidators := []validator.Int64{
+		int64validator.AtLeast(1),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Lifecycle configuration of a bucket. The storage classes of transitions are validated against the placement target of the bucket at plan time, as RGW accepts rules with unknown storage classes but never transitions any object. Upon deletion, the lifecycle configuration is removed. Rules are matched by their id when the configuration is read, so reordering or adding rules only shows the affected rules in the plan. Existing configurations can be imported by the bucket name.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"rule": schema.ListNestedAttribute{
+				MarkdownDescription: "Lifecycle rules",
+				Required:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							MarkdownDescription: "Unique id of the rule, rules are matched by their id",
+							Required:            true,
+						},
+						"enabled": schema.BoolAttribute{
+							MarkdownDescription: "Whether the rule is applied. Defaults to `true`.",
+							Optional:            true,
+							Computed:            true,
+							Default:             booldefault.StaticBool(true),
+						},
+						"prefix": schema.StringAttribute{
+							MarkdownDescription: "Only apply the rule to keys starting with the prefix",
+							Optional:            true,
+						},
+						"expiration_days": schema.Int64Attribute{
+							MarkdownDescription: "Delete objects this many days after their creation",
+							Optional:            true,
+							Validators:          daysValidators,
+						},
+						"noncurrent_expiration_days": schema.Int64Attribute{
+							MarkdownDescription: "Delete noncurrent object versions this many days after they became noncurrent",
+							Optional:            true,
+							Validators:          daysValidators,
+						},
+						"abort_incomplete_multipart_upload_days": schema.Int64Attribute{
+							MarkdownDescription: "Abort multipart uploads this many days after they were initiated",
+							Optional:            true,
+							Validators:          daysValidators,
+						},
+						"transition": schema.ListNestedAttribute{
+							MarkdownDescription: "Move objects to another storage class of the placement target",
+							Optional:            true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"days": schema.Int64Attribute{
+										MarkdownDescription: "Move objects this many days after their creation",
+										Required:            true,
+										Validators: []validator.Int64{
+											int64validator.AtLeast(0),
+										},
+									},
+									"storage_class": schema.StringAttribute{
+										MarkdownDescription: "The storage class to move objects to, e.g. `COLD`",
+										Required:            true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *BucketLifecycleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to do on destroy
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var bucket types.String
+	var rules types.List
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("bucket"), &bucket)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("rule"), &rules)...)
+	if resp.Diagnostics.HasError() || bucket.IsUnknown() || rules.IsUnknown() {
+		return
+	}
+
+	var data BucketLifecycleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateLifecycleRuleIDs(data.Rules)...)
+	resp.Diagnostics.Append(r.validateTransitions(ctx, &data)...)
+}
+
+// validateLifecycleRuleIDs checks that the ids of the rules are unique, as the rules are matched by id.
+func validateLifecycleRuleIDs(rules []BucketLifecycleRuleModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	seen := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		if rule.Id.IsUnknown() {
+			continue
+		}
+		id := rule.Id.ValueString()
+		if seen[id] {
+			diags.AddAttributeError(
+				path.Root("rule").AtListIndex(i).AtName("id"),
+				"duplicate lifecycle rule id",
+				fmt.Sprintf("lifecycle rule id '%s' is used more than once", id),
+			)
+		}
+		seen[id] = true
+	}
+
+	return diags
+}
+
+// validateTransitions checks the storage classes of the transitions against the placement
+// target of the bucket. Buckets which do not exist yet are not validated.
+func (r *BucketLifecycleResource) validateTransitions(ctx context.Context, data *BucketLifecycleResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	hasTransitions := false
+	for _, rule := range data.Rules {
+		hasTransitions = hasTransitions || len(rule.Transitions) > 0
+	}
+	if !hasTransitions {
+		return diags
+	}
+
+	bucket, err := getBucketInfo(ctx, r.client.Admin, data.Bucket.ValueString())
+	if err != nil {
+		if !errors.Is(err, admin.ErrNoSuchBucket) {
+			diags.AddWarning("could not validate lifecycle transitions", fmt.Sprintf("could not get bucket info: %s", err.Error()))
+		}
+		return diags
+	}
+
+	zonegroupMap, err := getZonegroupMap(ctx, r.client.Admin)
+	if err != nil {
+		diags.AddWarning("could not validate lifecycle transitions", fmt.Sprintf("could not get zonegroup map: %s", err.Error()))
+		return diags
+	}
+
+	// placement rule is reported as "<placement>[/<storage class>]"
+	placement, _, _ := strings.Cut(bucket.PlacementRule, "/")
+	if placement == "" {
+		placement = zonegroupMap.defaultPlacement()
+	}
+	target, ok := zonegroupMap.placementTarget(placement)
+	if !ok {
+		diags.AddWarning("could not validate lifecycle transitions", fmt.Sprintf("placement target '%s' of the bucket does not exist in any zonegroup", placement))
+		return diags
+	}
+
+	for i, rule := range data.Rules {
+		for j, t := range rule.Transitions {
+			if t.StorageClass.IsUnknown() || containsString(target.StorageClasses, t.StorageClass.ValueString()) {
+				continue
+			}
+			diags.AddAttributeError(
+				path.Root("rule").AtListIndex(i).AtName("transition").AtListIndex(j).AtName("storage_class"),
+				"unknown storage class",
+				fmt.Sprintf("storage class '%s' is not available in placement target '%s' of bucket %s, available: %s", t.StorageClass.ValueString(), placement, data.Bucket.ValueString(), strings.Join(target.StorageClasses, ", ")),
+			)
+		}
+	}
+
+	return diags
+}
+
+func (r *BucketLifecycleResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_lifecycle")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.client = client
+}
+
+func (r *BucketLifecycleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// the lifecycle configuration is imported by the bucket name
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("bucket"), req.ID)...)
+}
+
+// isNoSuchLifecycleConfiguration reports whether err signals that a bucket has no lifecycle configuration.
+func isNoSuchLifecycleConfiguration(err error) bool {
+	var ae smithy.APIError
+	return errors.As(err, &ae) && ae.ErrorCode() == "NoSuchLifecycleConfiguration"
+}
+
+// putLifecycle replaces the lifecycle configuration of the bucket.
+func (r *BucketLifecycleResource) putLifecycle(ctx context.Context, data *BucketLifecycleResourceModel) error {
+	config := &s3types.BucketLifecycleConfiguration{}
+	for _, rule := range data.Rules {
+		lr := s3types.LifecycleRule{
+			ID:     aws.String(rule.Id.ValueString()),
+			Status: s3types.ExpirationStatusDisabled,
+			Filter: &s3types.LifecycleRuleFilterMemberPrefix{Value: rule.Prefix.ValueString()},
+		}
+		if rule.Enabled.ValueBool() {
+			lr.Status = s3types.ExpirationStatusEnabled
+		}
+		if !rule.ExpirationDays.IsNull() {
+			lr.Expiration = &s3types.LifecycleExpiration{Days: int32(rule.ExpirationDays.ValueInt64())}
+		}
+		if !rule.NoncurrentExpirationDays.IsNull() {
+			lr.NoncurrentVersionExpiration = &s3types.NoncurrentVersionExpiration{NoncurrentDays: int32(rule.NoncurrentExpirationDays.ValueInt64())}
+		}
+		if !rule.AbortMultipartDays.IsNull() {
+			lr.AbortIncompleteMultipartUpload = &s3types.AbortIncompleteMultipartUpload{DaysAfterInitiation: int32(rule.AbortMultipartDays.ValueInt64())}
+		}
+		for _, t := range rule.Transitions {
+			lr.Transitions = append(lr.Transitions, s3types.Transition{
+				Days:         int32(t.Days.ValueInt64()),
+				StorageClass: s3types.TransitionStorageClass(t.StorageClass.ValueString()),
+			})
+		}
+		config.Rules = append(config.Rules, lr)
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("put %d lifecycle rules to bucket %s", len(config.Rules), data.Bucket.ValueString()))
+
+	_, err := r.client.S3.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(data.Bucket.ValueString()),
+		LifecycleConfiguration: config,
+	})
+	return err
+}
+
+// lifecycleRuleFromS3 returns the resource attributes of a lifecycle rule returned by the gateway.
+func lifecycleRuleFromS3(lr s3types.LifecycleRule) BucketLifecycleRuleModel {
+	rule := BucketLifecycleRuleModel{
+		Id:                       types.StringValue(aws.StringValue(lr.ID)),
+		Enabled:                  types.BoolValue(lr.Status == s3types.ExpirationStatusEnabled),
+		Prefix:                   stringOrNull(lr.Prefix),
+		ExpirationDays:           types.Int64Null(),
+		NoncurrentExpirationDays: types.Int64Null(),
+		AbortMultipartDays:       types.Int64Null(),
+	}
+	if filter, ok := lr.Filter.(*s3types.LifecycleRuleFilterMemberPrefix); ok && filter.Value != "" {
+		rule.Prefix = types.StringValue(filter.Value)
+	}
+	if lr.Expiration != nil && lr.Expiration.Days > 0 {
+		rule.ExpirationDays = types.Int64Value(int64(lr.Expiration.Days))
+	}
+	if lr.NoncurrentVersionExpiration != nil {
+		rule.NoncurrentExpirationDays = types.Int64Value(int64(lr.NoncurrentVersionExpiration.NoncurrentDays))
+	}
+	if lr.AbortIncompleteMultipartUpload != nil {
+		rule.AbortMultipartDays = types.Int64Value(int64(lr.AbortIncompleteMultipartUpload.DaysAfterInitiation))
+	}
+	for _, t := range lr.Transitions {
+		rule.Transitions = append(rule.Transitions, BucketLifecycleTransitionModel{
+			Days:         types.Int64Value(int64(t.Days)),
+			StorageClass: types.StringValue(string(t.StorageClass)),
+		})
+	}
+	return rule
+}
+
+// orderLifecycleRules orders the rules returned by the gateway like the prior rules by id,
+// so the plan only shows the rules which actually changed. Rules unknown to the prior
+// state, e.g. on import or when added outside of terraform, are appended in the order
+// of the gateway.
+func orderLifecycleRules(rules []BucketLifecycleRuleModel, prior []BucketLifecycleRuleModel) []BucketLifecycleRuleModel {
+	byID := make(map[string]BucketLifecycleRuleModel, len(rules))
+	for _, rule := range rules {
+		byID[rule.Id.ValueString()] = rule
+	}
+
+	ordered := make([]BucketLifecycleRuleModel, 0, len(rules))
+	for _, p := range prior {
+		if rule, ok := byID[p.Id.ValueString()]; ok {
+			ordered = append(ordered, rule)
+			delete(byID, p.Id.ValueString())
+		}
+	}
+	for _, rule := range rules {
+		if _, ok := byID[rule.Id.ValueString()]; ok {
+			ordered = append(ordered, rule)
+		}
+	}
+	return ordered
+}
+
+func (r *BucketLifecycleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketLifecycleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := retryNoSuchBucket(ctx, func() error {
+		return r.putLifecycle(ctx, data)
+	})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not put bucket lifecycle", err)...)
+		return
+	}
+
+	// use bucket name as resource id
+	data.Id = types.StringValue(data.Bucket.ValueString())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketLifecycleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *BucketLifecycleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	out, err := r.client.S3.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+	})
+	if err != nil {
+		if isNoSuchBucket(err) || isNoSuchLifecycleConfiguration(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket lifecycle", err)...)
+		return
+	}
+
+	rules := make([]BucketLifecycleRuleModel, len(out.Rules))
+	for i, lr := range out.Rules {
+		rules[i] = lifecycleRuleFromS3(lr)
+	}
+	data.Rules = orderLifecycleRules(rules, data.Rules)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketLifecycleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketLifecycleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.putLifecycle(ctx, data); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not put bucket lifecycle", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketLifecycleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *BucketLifecycleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := r.client.S3.DeleteBucketLifecycle(ctx, &s3.DeleteBucketLifecycleInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+	})
+	if err != nil && !isNoSuchBucket(err) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete bucket lifecycle", err)...)
+		return
+	}
+}
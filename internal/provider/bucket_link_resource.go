@@ -6,11 +6,15 @@ import (
 	"fmt"
 
 	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
@@ -28,6 +32,18 @@ type BucketLinkResourceModel struct {
 	UID         types.String `tfsdk:"uid"`
 	Bucket      types.String `tfsdk:"bucket"`
 	UnlinkToUID types.String `tfsdk:"unlink_to_uid"`
+	BucketID    types.String `tfsdk:"bucket_id"`
+	NewName     types.String `tfsdk:"new_bucket_name"`
+	PrevOwner   types.String `tfsdk:"previous_owner"`
+	OnDestroy   types.String `tfsdk:"on_destroy"`
+}
+
+// currentBucket returns the name of the bucket after the link operation.
+func (data *BucketLinkResourceModel) currentBucket() string {
+	if data.NewName.ValueString() != "" {
+		return data.NewName.ValueString()
+	}
+	return data.Bucket.ValueString()
 }
 
 func (r *BucketLinkResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -36,15 +52,12 @@ func (r *BucketLinkResource) Metadata(ctx context.Context, req resource.Metadata
 
 func (r *BucketLinkResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Ceph RGW Bucket Link. This resource allow to change bucket ownership in Ceph. It supports reverting ownership upon resource destruction",
+		MarkdownDescription: "Ceph RGW Bucket Link. This resource allow to change bucket ownership in Ceph. It supports reverting ownership upon resource destruction: the bucket is linked to `unlink_to_uid` if set, and to the `previous_owner` otherwise. See `on_destroy` to unlink the bucket or keep the link instead.",
 
 		Attributes: map[string]schema.Attribute{
 			"uid": schema.StringAttribute{
-				MarkdownDescription: "The user ID to be linked with a bucket",
+				MarkdownDescription: "The user ID to be linked with a bucket. Changing it relinks the bucket in place.",
 				Required:            true,
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
 			},
 			"bucket": schema.StringAttribute{
 				MarkdownDescription: "The bucket name to link with a user",
@@ -54,8 +67,35 @@ func (r *BucketLinkResource) Schema(ctx context.Context, req resource.SchemaRequ
 				},
 			},
 			"unlink_to_uid": schema.StringAttribute{
-				MarkdownDescription: "The UID of a user to link bucket to when resource is destroyed",
+				MarkdownDescription: "The UID of a user to link bucket to when resource is destroyed. Defaults to `previous_owner`, the bucket is unlinked if neither is known.",
+				Optional:            true,
+			},
+			"new_bucket_name": schema.StringAttribute{
+				MarkdownDescription: "Rename the bucket to this name as part of the link operation. Unsetting it renames the bucket back to `bucket`.",
+				Optional:            true,
+			},
+			"on_destroy": schema.StringAttribute{
+				MarkdownDescription: "What happens to the bucket when the resource is destroyed: `relink` links it to `unlink_to_uid` or `previous_owner` (and unlinks it if neither is known), `unlink` unlinks it from `uid`, `noop` only removes the resource from the state and keeps the link on the gateway. Defaults to `relink`.",
 				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("relink"),
+				Validators: []validator.String{
+					stringvalidator.OneOf("relink", "unlink", "noop"),
+				},
+			},
+			"previous_owner": schema.StringAttribute{
+				MarkdownDescription: "The UID of the user owning the bucket before it was linked",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"bucket_id": schema.StringAttribute{
+				MarkdownDescription: "The bucket instance id to link, for precise targeting of tenanted or resharded buckets",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 		},
 	}
@@ -82,6 +122,11 @@ func (r *BucketLinkResource) Configure(ctx context.Context, req resource.Configu
 }
 
 func (r *BucketLinkResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *BucketLinkResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -89,16 +134,25 @@ func (r *BucketLinkResource) Create(ctx context.Context, req resource.CreateRequ
 		return
 	}
 
+	// remember the owner to restore it on destroy
+	bucket, err := getBucketInfo(ctx, r.client.Admin, data.Bucket.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket owner", err)...)
+		return
+	}
+	data.PrevOwner = types.StringValue(bucket.Owner)
+
 	// Create API user object
 	rgwBucketLink := admin.BucketLinkInput{
-		Bucket: data.Bucket.ValueString(),
-		UID:    data.UID.ValueString(),
+		Bucket:   data.Bucket.ValueString(),
+		BucketID: data.BucketID.ValueString(),
+		UID:      data.UID.ValueString(),
 	}
 
 	// create bucket link
-	err := r.client.Admin.LinkBucket(ctx, rgwBucketLink)
+	err = linkBucket(ctx, r.client.Admin, rgwBucketLink, data.NewName.ValueString())
 	if err != nil {
-		resp.Diagnostics.AddError("could not create bucket link", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create bucket link", err)...)
 		return
 	}
 
@@ -122,7 +176,7 @@ func (r *BucketLinkResource) Read(ctx context.Context, req resource.ReadRequest,
 			resp.State.RemoveResource(ctx)
 			return
 		}
-		resp.Diagnostics.AddError("could not get user's buckets", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get user's buckets", err)...)
 		return
 	}
 
@@ -135,17 +189,27 @@ func (r *BucketLinkResource) Read(ctx context.Context, req resource.ReadRequest,
 		return false
 	}
 
-	if !findString(buckets, data.Bucket.ValueString()) {
+	if !findString(buckets, data.currentBucket()) {
 		// Remove bucket link from state
 		resp.State.RemoveResource(ctx)
 		return
 	}
 
+	// the owner is unknown for links created before it was recorded, they are unlinked on destroy as before
+	if data.PrevOwner.IsNull() {
+		data.PrevOwner = types.StringValue("")
+	}
+
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *BucketLinkResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *BucketLinkResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -153,13 +217,38 @@ func (r *BucketLinkResource) Update(ctx context.Context, req resource.UpdateRequ
 		return
 	}
 
-	// Currently there is nothing to update in place
+	var dataState *BucketLinkResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &dataState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the previous owner is only recorded on creation
+	data.PrevOwner = dataState.PrevOwner
+
+	// relink bucket to the new owner, this implicitly unlinks it from the previous one
+	if !data.UID.Equal(dataState.UID) || data.currentBucket() != dataState.currentBucket() {
+		err := linkBucket(ctx, r.client.Admin, admin.BucketLinkInput{
+			Bucket:   dataState.currentBucket(),
+			BucketID: data.BucketID.ValueString(),
+			UID:      data.UID.ValueString(),
+		}, data.currentBucket())
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify bucket link", err)...)
+			return
+		}
+	}
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *BucketLinkResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform prior state data into the model
 	var data *BucketLinkResourceModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -167,22 +256,36 @@ func (r *BucketLinkResource) Delete(ctx context.Context, req resource.DeleteRequ
 		return
 	}
 
-	var err error
+	if data.OnDestroy.ValueString() == "noop" {
+		tflog.Info(ctx, fmt.Sprintf("keep bucket %s linked to %s", data.currentBucket(), data.UID.ValueString()))
+		return
+	}
+
+	unlinkTo := data.UnlinkToUID.ValueString()
 	if data.UnlinkToUID.IsNull() {
+		unlinkTo = data.PrevOwner.ValueString()
+	}
+	if data.OnDestroy.ValueString() == "unlink" {
+		unlinkTo = ""
+	}
+
+	var err error
+	if unlinkTo == "" {
 		// send delete request to api
 		err = r.client.Admin.UnlinkBucket(ctx, admin.BucketLinkInput{
-			Bucket: data.Bucket.ValueString(),
+			Bucket: data.currentBucket(),
 			UID:    data.UID.ValueString(),
 		})
 	} else {
 		// send link request to api
 		err = r.client.Admin.LinkBucket(ctx, admin.BucketLinkInput{
-			Bucket: data.Bucket.ValueString(),
-			UID:    data.UnlinkToUID.ValueString(),
+			Bucket:   data.currentBucket(),
+			BucketID: data.BucketID.ValueString(),
+			UID:      unlinkTo,
 		})
 	}
 	if err != nil && !errors.Is(err, admin.ErrNoSuchBucket) {
-		resp.Diagnostics.AddError("could not delete bucket link", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete bucket link", err)...)
 		return
 	}
 }
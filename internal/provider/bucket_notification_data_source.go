@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &BucketNotificationDataSource{}
+
+func NewBucketNotificationDataSource() datasource.DataSource {
+	return &BucketNotificationDataSource{}
+}
+
+type BucketNotificationDataSource struct {
+	client *RgwClient
+}
+
+type BucketNotificationDataSourceModel struct {
+	Id     types.String                   `tfsdk:"id"`
+	Bucket types.String                   `tfsdk:"bucket"`
+	Topics []BucketNotificationTopicModel `tfsdk:"topic"`
+}
+
+func (d *BucketNotificationDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_notification"
+}
+
+func (d *BucketNotificationDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Current notification configuration of a bucket, regardless of whether it is managed by `rgw_bucket_notification`. Use it in `check` blocks to verify that events of a bucket are routed to the expected topics.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+			},
+			"topic": schema.ListNestedAttribute{
+				MarkdownDescription: "Notifications sent to a topic, empty if the bucket has no notifications",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							MarkdownDescription: "Unique name of the notification",
+							Computed:            true,
+						},
+						"topic_arn": schema.StringAttribute{
+							MarkdownDescription: "ARN of the topic",
+							Computed:            true,
+						},
+						"events": schema.SetAttribute{
+							MarkdownDescription: "Events to notify about, e.g. `s3:ObjectCreated:*`",
+							ElementType:         types.StringType,
+							Computed:            true,
+						},
+						"filter_prefix": schema.StringAttribute{
+							MarkdownDescription: "Only notify about objects with keys starting with the prefix",
+							Computed:            true,
+						},
+						"filter_suffix": schema.StringAttribute{
+							MarkdownDescription: "Only notify about objects with keys ending with the suffix",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *BucketNotificationDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the data source can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_notification")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.client = client
+}
+
+func (d *BucketNotificationDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data BucketNotificationDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	out, err := d.client.S3.GetBucketNotificationConfiguration(ctx, &s3.GetBucketNotificationConfigurationInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+	})
+	if err != nil {
+		if isNoSuchBucket(err) {
+			resp.Diagnostics.AddAttributeError(path.Root("bucket"), "bucket not found", fmt.Sprintf("bucket %s does not exist", data.Bucket.ValueString()))
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(d.client, "could not get bucket notifications", err)...)
+		return
+	}
+
+	data.Id = types.StringValue(data.Bucket.ValueString())
+	data.Topics = notificationTopicsFromS3(out.TopicConfigurations)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
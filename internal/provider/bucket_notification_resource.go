@@ -0,0 +1,532 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// notificationEvents are the event types supported by RGW bucket notifications.
+var notificationEvents = []string{
+	"s3:ObjectCreated:*",
+	"s3:ObjectCreated:Put",
+	"s3:ObjectCreated:Post",
+	"s3:ObjectCreated:Copy",
+	"s3:ObjectCreated:CompleteMultipartUpload",
+	"s3:ObjectRemoved:*",
+	"s3:ObjectRemoved:Delete",
+	"s3:ObjectRemoved:DeleteMarkerCreated",
+	"s3:ObjectLifecycle:Expiration:*",
+	"s3:ObjectLifecycle:Expiration:Current",
+	"s3:ObjectLifecycle:Expiration:NonCurrent",
+	"s3:ObjectLifecycle:Expiration:DeleteMarker",
+	"s3:ObjectLifecycle:Expiration:AbortMultipartUpload",
+	"s3:ObjectLifecycle:Transition:*",
+	"s3:ObjectLifecycle:Transition:Current",
+	"s3:ObjectLifecycle:Transition:NonCurrent",
+	"s3:ObjectSynced:*",
+	"s3:ObjectSynced:Create",
+	"s3:ObjectSynced:Delete",
+	"s3:ObjectSynced:DeletionMarkerCreated",
+}
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &BucketNotificationResource{}
+var _ resource.ResourceWithValidateConfig = &BucketNotificationResource{}
+var _ resource.ResourceWithModifyPlan = &BucketNotificationResource{}
+
+func NewBucketNotificationResource() resource.Resource {
+	return &BucketNotificationResource{}
+}
+
+type BucketNotificationResource struct {
+	client *RgwClient
+}
+
+type BucketNotificationResourceModel struct {
+	Id        types.String                   `tfsdk:"id"`
+	Bucket    types.String                   `tfsdk:"bucket"`
+	Topics    []BucketNotificationTopicModel `tfsdk:"topic"`
+	Exclusive types.Bool                     `tfsdk:"exclusive"`
+}
+
+type BucketNotificationTopicModel struct {
+	Id           types.String   `tfsdk:"id"`
+	TopicArn     types.String   `tfsdk:"topic_arn"`
+	Events       []types.String `tfsdk:"events"`
+	FilterPrefix types.String   `tfsdk:"filter_prefix"`
+	FilterSuffix types.String   `tfsdk:"filter_suffix"`
+}
+
+func (r *BucketNotificationResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_notification"
+}
+
+func (r *BucketNotificationResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Notification configuration of a bucket, sending events to topics created with `rgw_topic`. RGW merges the configuration with the notifications already set on the bucket, notifications removed from `topic` are deleted. Notifications created outside of this resource are ignored unless `exclusive` is set. Event names and overlapping filters are validated at plan time, as RGW accepts some invalid configurations and never delivers their events.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"exclusive": schema.BoolAttribute{
+				MarkdownDescription: "Make this resource authoritative for the notifications of the bucket. Notifications created outside of this resource, e.g. stale hooks of other tools, show up as drift on refresh and are deleted on apply and when the resource is destroyed. Defaults to `false`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"topic": schema.ListNestedAttribute{
+				MarkdownDescription: "Notifications sent to a topic",
+				Required:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							MarkdownDescription: "Unique name of the notification",
+							Required:            true,
+						},
+						"topic_arn": schema.StringAttribute{
+							MarkdownDescription: "ARN of the topic, e.g. the `arn` attribute of a `rgw_topic`",
+							Required:            true,
+						},
+						"events": schema.SetAttribute{
+							MarkdownDescription: "Events to notify about, e.g. `s3:ObjectCreated:*`",
+							ElementType:         types.StringType,
+							Required:            true,
+						},
+						"filter_prefix": schema.StringAttribute{
+							MarkdownDescription: "Only notify about objects with keys starting with the prefix",
+							Optional:            true,
+						},
+						"filter_suffix": schema.StringAttribute{
+							MarkdownDescription: "Only notify about objects with keys ending with the suffix",
+							Optional:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// notificationEventsOverlap reports whether two event types match a common event, considering wildcards.
+func notificationEventsOverlap(a, b string) bool {
+	a, b = strings.TrimSuffix(a, "*"), strings.TrimSuffix(b, "*")
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// notificationFiltersOverlap reports whether two key filters match a common key.
+func notificationFiltersOverlap(a, b BucketNotificationTopicModel) bool {
+	prefixA, prefixB := a.FilterPrefix.ValueString(), b.FilterPrefix.ValueString()
+	suffixA, suffixB := a.FilterSuffix.ValueString(), b.FilterSuffix.ValueString()
+	prefixes := strings.HasPrefix(prefixA, prefixB) || strings.HasPrefix(prefixB, prefixA)
+	suffixes := strings.HasSuffix(suffixA, suffixB) || strings.HasSuffix(suffixB, suffixA)
+	return prefixes && suffixes
+}
+
+func (r *BucketNotificationResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	// notifications are only validated once they are known
+	var topics types.List
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("topic"), &topics)...)
+	if resp.Diagnostics.HasError() || topics.IsUnknown() {
+		return
+	}
+
+	var data BucketNotificationResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ids := make(map[string]bool)
+	for i, t := range data.Topics {
+		if !t.Id.IsUnknown() {
+			if ids[t.Id.ValueString()] {
+				resp.Diagnostics.AddAttributeError(path.Root("topic").AtListIndex(i).AtName("id"), "duplicate notification id", fmt.Sprintf("notification id '%s' is used more than once", t.Id.ValueString()))
+			}
+			ids[t.Id.ValueString()] = true
+		}
+
+		for _, e := range t.Events {
+			if !e.IsUnknown() && !containsString(notificationEvents, e.ValueString()) {
+				resp.Diagnostics.AddAttributeError(path.Root("topic").AtListIndex(i).AtName("events"), "unsupported event", fmt.Sprintf("event '%s' is not supported by RGW, supported: %s", e.ValueString(), strings.Join(notificationEvents, ", ")))
+			}
+		}
+	}
+
+	// notifications for the same events on overlapping keys are ambiguous
+	for i := range data.Topics {
+		for j := i + 1; j < len(data.Topics); j++ {
+			a, b := data.Topics[i], data.Topics[j]
+			if a.FilterPrefix.IsUnknown() || a.FilterSuffix.IsUnknown() || b.FilterPrefix.IsUnknown() || b.FilterSuffix.IsUnknown() {
+				continue
+			}
+			if !notificationFiltersOverlap(a, b) {
+				continue
+			}
+			for _, ea := range a.Events {
+				for _, eb := range b.Events {
+					if ea.IsUnknown() || eb.IsUnknown() || !notificationEventsOverlap(ea.ValueString(), eb.ValueString()) {
+						continue
+					}
+					resp.Diagnostics.AddAttributeError(path.Root("topic").AtListIndex(j), "overlapping notifications", fmt.Sprintf("notifications '%s' and '%s' have overlapping filters for events '%s' and '%s'", a.Id.ValueString(), b.Id.ValueString(), ea.ValueString(), eb.ValueString()))
+				}
+			}
+		}
+	}
+}
+
+func (r *BucketNotificationResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var topics types.List
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("topic"), &topics)...)
+	if resp.Diagnostics.HasError() || topics.IsUnknown() {
+		return
+	}
+
+	var data BucketNotificationResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// lifecycle and sync events are supported since reef
+	for i, t := range data.Topics {
+		for _, e := range t.Events {
+			event := e.ValueString()
+			if strings.HasPrefix(event, "s3:ObjectLifecycle:") || strings.HasPrefix(event, "s3:ObjectSynced:") {
+				for _, d := range requireCephRelease(r.client, 18, fmt.Sprintf("event '%s'", event)) {
+					resp.Diagnostics.AddAttributeError(path.Root("topic").AtListIndex(i).AtName("events"), d.Summary(), d.Detail())
+				}
+			}
+		}
+	}
+}
+
+func (r *BucketNotificationResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_notification")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.client = client
+}
+
+// putNotifications replaces the notification configuration of the bucket.
+func (r *BucketNotificationResource) putNotifications(ctx context.Context, data *BucketNotificationResourceModel) error {
+	config := &s3types.NotificationConfiguration{}
+	for _, t := range data.Topics {
+		tc := s3types.TopicConfiguration{
+			Id:       aws.String(t.Id.ValueString()),
+			TopicArn: aws.String(t.TopicArn.ValueString()),
+		}
+		for _, e := range t.Events {
+			tc.Events = append(tc.Events, s3types.Event(e.ValueString()))
+		}
+
+		var rules []s3types.FilterRule
+		if t.FilterPrefix.ValueString() != "" {
+			rules = append(rules, s3types.FilterRule{Name: s3types.FilterRuleNamePrefix, Value: aws.String(t.FilterPrefix.ValueString())})
+		}
+		if t.FilterSuffix.ValueString() != "" {
+			rules = append(rules, s3types.FilterRule{Name: s3types.FilterRuleNameSuffix, Value: aws.String(t.FilterSuffix.ValueString())})
+		}
+		if len(rules) > 0 {
+			tc.Filter = &s3types.NotificationConfigurationFilter{
+				Key: &s3types.S3KeyFilter{FilterRules: rules},
+			}
+		}
+
+		config.TopicConfigurations = append(config.TopicConfigurations, tc)
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("put %d notifications to bucket %s", len(config.TopicConfigurations), data.Bucket.ValueString()))
+
+	_, err := r.client.S3.PutBucketNotificationConfiguration(ctx, &s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    aws.String(data.Bucket.ValueString()),
+		NotificationConfiguration: config,
+	})
+	return err
+}
+
+// deleteNotifications deletes notifications of the bucket by id. RGW merges configurations
+// on put, so notifications have to be deleted with its extension of the s3 api.
+func (r *BucketNotificationResource) deleteNotifications(ctx context.Context, bucket string, ids []string) error {
+	for _, id := range ids {
+		tflog.Info(ctx, fmt.Sprintf("delete notification %s of bucket %s", id, bucket))
+		_, err := signedCall(ctx, r.client.Admin, http.MethodDelete, "/"+url.PathEscape(bucket), url.Values{
+			"notification": {id},
+		})
+		if err != nil {
+			return fmt.Errorf("could not delete notification %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// deleteAllNotifications deletes all notifications of the bucket, as RGW does not remove
+// notifications on put of an empty configuration.
+func (r *BucketNotificationResource) deleteAllNotifications(ctx context.Context, bucket string) error {
+	tflog.Info(ctx, fmt.Sprintf("delete all notifications of bucket %s", bucket))
+	_, err := signedCall(ctx, r.client.Admin, http.MethodDelete, "/"+url.PathEscape(bucket)+"?notification", nil)
+	return err
+}
+
+// staleNotifications returns the ids of the notifications of the bucket which are not
+// configured in data. Only notifications of the prior state are considered stale unless
+// the resource is exclusive, then every notification on the gateway is.
+func (r *BucketNotificationResource) staleNotifications(ctx context.Context, data *BucketNotificationResourceModel, prior []BucketNotificationTopicModel) ([]string, error) {
+	configured := make(map[string]bool, len(data.Topics))
+	for _, t := range data.Topics {
+		configured[t.Id.ValueString()] = true
+	}
+
+	candidates := prior
+	if data.Exclusive.ValueBool() {
+		out, err := r.client.S3.GetBucketNotificationConfiguration(ctx, &s3.GetBucketNotificationConfigurationInput{
+			Bucket: aws.String(data.Bucket.ValueString()),
+		})
+		if err != nil {
+			return nil, err
+		}
+		candidates = notificationTopicsFromS3(out.TopicConfigurations)
+	}
+
+	var stale []string
+	for _, t := range candidates {
+		if !configured[t.Id.ValueString()] {
+			stale = append(stale, t.Id.ValueString())
+		}
+	}
+	return stale, nil
+}
+
+// notificationTopicsFromS3 returns the resource attributes of the notifications returned by the gateway.
+func notificationTopicsFromS3(configs []s3types.TopicConfiguration) []BucketNotificationTopicModel {
+	topics := make([]BucketNotificationTopicModel, len(configs))
+	for i, tc := range configs {
+		topics[i] = BucketNotificationTopicModel{
+			Id:           types.StringValue(aws.StringValue(tc.Id)),
+			TopicArn:     types.StringValue(aws.StringValue(tc.TopicArn)),
+			FilterPrefix: types.StringNull(),
+			FilterSuffix: types.StringNull(),
+		}
+		for _, e := range tc.Events {
+			topics[i].Events = append(topics[i].Events, types.StringValue(string(e)))
+		}
+		if tc.Filter != nil && tc.Filter.Key != nil {
+			for _, rule := range tc.Filter.Key.FilterRules {
+				switch strings.ToLower(string(rule.Name)) {
+				case string(s3types.FilterRuleNamePrefix):
+					topics[i].FilterPrefix = types.StringValue(aws.StringValue(rule.Value))
+				case string(s3types.FilterRuleNameSuffix):
+					topics[i].FilterSuffix = types.StringValue(aws.StringValue(rule.Value))
+				}
+			}
+		}
+	}
+	return topics
+}
+
+func (r *BucketNotificationResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketNotificationResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := retryNoSuchBucket(ctx, func() error {
+		return r.putNotifications(ctx, data)
+	})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not put bucket notifications", err)...)
+		return
+	}
+
+	// remove notifications not created by this resource if it is exclusive
+	stale, err := r.staleNotifications(ctx, data, nil)
+	if err == nil {
+		err = r.deleteNotifications(ctx, data.Bucket.ValueString(), stale)
+	}
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete stale bucket notifications", err)...)
+		return
+	}
+
+	// use bucket name as resource id
+	data.Id = types.StringValue(data.Bucket.ValueString())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketNotificationResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *BucketNotificationResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	out, err := r.client.S3.GetBucketNotificationConfiguration(ctx, &s3.GetBucketNotificationConfigurationInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+	})
+	if err != nil {
+		if isNoSuchBucket(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket notifications", err)...)
+		return
+	}
+
+	// notifications created outside of this resource are only managed if it is exclusive
+	topics := notificationTopicsFromS3(out.TopicConfigurations)
+	if !data.Exclusive.ValueBool() {
+		managed := make(map[string]bool, len(data.Topics))
+		for _, t := range data.Topics {
+			managed[t.Id.ValueString()] = true
+		}
+		var owned []BucketNotificationTopicModel
+		for _, t := range topics {
+			if managed[t.Id.ValueString()] {
+				owned = append(owned, t)
+			}
+		}
+		topics = owned
+	}
+	if len(topics) == 0 {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	data.Topics = topics
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketNotificationResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketNotificationResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var dataState *BucketNotificationResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &dataState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.putNotifications(ctx, data); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not put bucket notifications", err)...)
+		return
+	}
+
+	// RGW merges the configuration, so removed notifications have to be deleted explicitly
+	stale, err := r.staleNotifications(ctx, data, dataState.Topics)
+	if err == nil {
+		err = r.deleteNotifications(ctx, data.Bucket.ValueString(), stale)
+	}
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete stale bucket notifications", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketNotificationResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *BucketNotificationResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// only remove the notifications of this resource unless it is exclusive
+	var err error
+	if data.Exclusive.ValueBool() {
+		err = r.deleteAllNotifications(ctx, data.Bucket.ValueString())
+	} else {
+		ids := make([]string, len(data.Topics))
+		for i, t := range data.Topics {
+			ids[i] = t.Id.ValueString()
+		}
+		err = r.deleteNotifications(ctx, data.Bucket.ValueString(), ids)
+	}
+	if err != nil && !isNoSuchBucket(err) && !errors.Is(err, admin.ErrNoSuchBucket) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete bucket notifications", err)...)
+		return
+	}
+}
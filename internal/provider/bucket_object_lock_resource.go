@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &BucketObjectLockResource{}
+
+func NewBucketObjectLockResource() resource.Resource {
+	return &BucketObjectLockResource{}
+}
+
+type BucketObjectLockResource struct {
+	client *RgwClient
+}
+
+type BucketObjectLockResourceModel struct {
+	Bucket types.String `tfsdk:"bucket"`
+	Mode   types.String `tfsdk:"mode"`
+	Days   types.Int64  `tfsdk:"days"`
+	Years  types.Int64  `tfsdk:"years"`
+}
+
+func (r *BucketObjectLockResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_object_lock"
+}
+
+func (r *BucketObjectLockResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	periodValidators := []validator.Int64{
+		int64validator.AtLeast(1),
+		int64validator.ExactlyOneOf(path.MatchRoot("days"), path.MatchRoot("years")),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Default object lock retention of a bucket. Object lock must have been enabled when the bucket was created. Upon deletion, the default retention is removed.",
+
+		Attributes: map[string]schema.Attribute{
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"mode": schema.StringAttribute{
+				MarkdownDescription: "Default retention mode - can be either `GOVERNANCE` or `COMPLIANCE`",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(string(s3types.ObjectLockRetentionModeGovernance), string(s3types.ObjectLockRetentionModeCompliance)),
+				},
+			},
+			"days": schema.Int64Attribute{
+				MarkdownDescription: "Default retention period in days. Exactly one of `days` or `years` is required.",
+				Optional:            true,
+				Validators:          periodValidators,
+			},
+			"years": schema.Int64Attribute{
+				MarkdownDescription: "Default retention period in years. Exactly one of `days` or `years` is required.",
+				Optional:            true,
+				Validators:          periodValidators,
+			},
+		},
+	}
+}
+
+func (r *BucketObjectLockResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_object_lock")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.client = client
+}
+
+// putObjectLockRetention sets the default retention of the bucket, removing it if retention is nil.
+func (r *BucketObjectLockResource) putObjectLockRetention(ctx context.Context, bucket string, retention *s3types.DefaultRetention) error {
+	config := &s3types.ObjectLockConfiguration{
+		ObjectLockEnabled: s3types.ObjectLockEnabledEnabled,
+	}
+	if retention != nil {
+		config.Rule = &s3types.ObjectLockRule{
+			DefaultRetention: retention,
+		}
+	}
+
+	_, err := r.client.S3.PutObjectLockConfiguration(ctx, &s3.PutObjectLockConfigurationInput{
+		Bucket:                  aws.String(bucket),
+		ObjectLockConfiguration: config,
+	})
+	return err
+}
+
+func retentionFromModel(data *BucketObjectLockResourceModel) *s3types.DefaultRetention {
+	return &s3types.DefaultRetention{
+		Mode:  s3types.ObjectLockRetentionMode(data.Mode.ValueString()),
+		Days:  int32(data.Days.ValueInt64()),
+		Years: int32(data.Years.ValueInt64()),
+	}
+}
+
+func (r *BucketObjectLockResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketObjectLockResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.putObjectLockRetention(ctx, data.Bucket.ValueString(), retentionFromModel(data))
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not set object lock retention", err)...)
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketObjectLockResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *BucketObjectLockResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	s3res, err := r.client.S3.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+	})
+	if err != nil {
+		var ae smithy.APIError
+		if isNoSuchBucket(err) || (errors.As(err, &ae) && ae.ErrorCode() == "ObjectLockConfigurationNotFoundError") {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get object lock configuration", err)...)
+		return
+	}
+
+	// default retention was removed outside of terraform
+	if s3res.ObjectLockConfiguration == nil || s3res.ObjectLockConfiguration.Rule == nil || s3res.ObjectLockConfiguration.Rule.DefaultRetention == nil {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	retention := s3res.ObjectLockConfiguration.Rule.DefaultRetention
+	data.Mode = types.StringValue(string(retention.Mode))
+	data.Days = types.Int64Null()
+	data.Years = types.Int64Null()
+	if retention.Days > 0 {
+		data.Days = types.Int64Value(int64(retention.Days))
+	}
+	if retention.Years > 0 {
+		data.Years = types.Int64Value(int64(retention.Years))
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketObjectLockResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketObjectLockResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.putObjectLockRetention(ctx, data.Bucket.ValueString(), retentionFromModel(data))
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify object lock retention", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketObjectLockResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *BucketObjectLockResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// object lock can not be disabled, only the default retention is removed
+	err := r.putObjectLockRetention(ctx, data.Bucket.ValueString(), nil)
+	if err != nil && !isNoSuchBucket(err) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not remove object lock retention", err)...)
+		return
+	}
+}
@@ -0,0 +1,230 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &BucketObjectLocksDataSource{}
+
+func NewBucketObjectLocksDataSource() datasource.DataSource {
+	return &BucketObjectLocksDataSource{}
+}
+
+type BucketObjectLocksDataSource struct {
+	client *RgwClient
+}
+
+type BucketObjectLocksDataSourceModel struct {
+	Id          types.String                  `tfsdk:"id"`
+	Tenant      types.String                  `tfsdk:"tenant"`
+	Concurrency types.Int64                   `tfsdk:"concurrency"`
+	Buckets     []BucketObjectLockReportModel `tfsdk:"buckets"`
+}
+
+type BucketObjectLockReportModel struct {
+	Name   types.String `tfsdk:"name"`
+	Tenant types.String `tfsdk:"tenant"`
+	Mode   types.String `tfsdk:"mode"`
+	Days   types.Int64  `tfsdk:"days"`
+	Years  types.Int64  `tfsdk:"years"`
+}
+
+func (d *BucketObjectLocksDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_object_locks"
+}
+
+func (d *BucketObjectLocksDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "All buckets with object lock enabled and their default retention, e.g. for WORM compliance reports. The buckets are listed with the admin API and their object lock configuration is fetched concurrently with the S3 API, so the provider credentials need access to every bucket, e.g. of a system user. Buckets which can not be checked fail the data source instead of being omitted.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"tenant": schema.StringAttribute{
+				MarkdownDescription: "Only report buckets of this tenant. If not set, the buckets of all tenants are reported.",
+				Optional:            true,
+			},
+			"concurrency": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of concurrent api calls. Defaults to `10`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, 100),
+				},
+			},
+			"buckets": schema.ListNestedAttribute{
+				MarkdownDescription: "The buckets with object lock enabled, sorted by tenant and name",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Bucket Name",
+							Computed:            true,
+						},
+						"tenant": schema.StringAttribute{
+							MarkdownDescription: "The tenant of the bucket, empty for buckets without tenant",
+							Computed:            true,
+						},
+						"mode": schema.StringAttribute{
+							MarkdownDescription: "Default retention mode, `GOVERNANCE` or `COMPLIANCE`. Null if the bucket has no default retention.",
+							Computed:            true,
+						},
+						"days": schema.Int64Attribute{
+							MarkdownDescription: "Default retention period in days",
+							Computed:            true,
+						},
+						"years": schema.Int64Attribute{
+							MarkdownDescription: "Default retention period in years",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *BucketObjectLocksDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the data source can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_object_locks")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.client = client
+}
+
+// getObjectLockReport returns the default retention of a bucket, false if object lock is not enabled.
+func (d *BucketObjectLocksDataSource) getObjectLockReport(ctx context.Context, tenant, name string) (BucketObjectLockReportModel, bool, error) {
+	report := BucketObjectLockReportModel{
+		Name:   types.StringValue(name),
+		Tenant: types.StringValue(tenant),
+		Mode:   types.StringNull(),
+		Days:   types.Int64Null(),
+		Years:  types.Int64Null(),
+	}
+
+	out, err := d.client.S3.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(tenantedS3Bucket(tenant, name)),
+	})
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "ObjectLockConfigurationNotFoundError" {
+			return report, false, nil
+		}
+		return report, false, err
+	}
+	if out.ObjectLockConfiguration == nil || out.ObjectLockConfiguration.ObjectLockEnabled != "Enabled" {
+		return report, false, nil
+	}
+
+	if rule := out.ObjectLockConfiguration.Rule; rule != nil && rule.DefaultRetention != nil {
+		retention := rule.DefaultRetention
+		report.Mode = types.StringValue(string(retention.Mode))
+		if retention.Days > 0 {
+			report.Days = types.Int64Value(int64(retention.Days))
+		}
+		if retention.Years > 0 {
+			report.Years = types.Int64Value(int64(retention.Years))
+		}
+	}
+	return report, true, nil
+}
+
+func (d *BucketObjectLocksDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data BucketObjectLocksDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	buckets, err := d.client.Admin.ListBuckets(ctx)
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(d.client, "could not list buckets", err)...)
+		return
+	}
+
+	// tenanted buckets are reported as "tenant/bucket"
+	keys := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		tenant, _, found := strings.Cut(bucket, "/")
+		if !found {
+			tenant = ""
+		}
+		if !data.Tenant.IsNull() && tenant != data.Tenant.ValueString() {
+			continue
+		}
+		keys = append(keys, bucket)
+	}
+
+	concurrency := defaultConcurrency
+	if !data.Concurrency.IsNull() {
+		concurrency = int(data.Concurrency.ValueInt64())
+	}
+
+	var mu sync.Mutex
+	reports := make([]BucketObjectLockReportModel, 0)
+	errs := forEachConcurrent(ctx, keys, concurrency, func(ctx context.Context, bucket string) error {
+		tenant, name, found := strings.Cut(bucket, "/")
+		if !found {
+			tenant, name = "", bucket
+		}
+		report, enabled, err := d.getObjectLockReport(ctx, tenant, name)
+		if err != nil || !enabled {
+			return err
+		}
+
+		mu.Lock()
+		reports = append(reports, report)
+		mu.Unlock()
+		return nil
+	})
+	if len(errs) > 0 {
+		resp.Diagnostics.AddError(fmt.Sprintf("could not get the object lock configuration of %d buckets", len(errs)), joinKeyErrors(errs))
+		return
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Tenant.ValueString() != reports[j].Tenant.ValueString() {
+			return reports[i].Tenant.ValueString() < reports[j].Tenant.ValueString()
+		}
+		return reports[i].Name.ValueString() < reports[j].Name.ValueString()
+	})
+	data.Buckets = reports
+	data.Id = types.StringValue(data.Tenant.ValueString())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
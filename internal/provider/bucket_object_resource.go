@@ -0,0 +1,509 @@
+package provider
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &BucketObjectResource{}
+var _ resource.ResourceWithValidateConfig = &BucketObjectResource{}
+
+func NewBucketObjectResource() resource.Resource {
+	return &BucketObjectResource{}
+}
+
+type BucketObjectResource struct {
+	client *RgwClient
+}
+
+type BucketObjectResourceModel struct {
+	Id                 types.String `tfsdk:"id"`
+	Bucket             types.String `tfsdk:"bucket"`
+	Key                types.String `tfsdk:"key"`
+	Content            types.String `tfsdk:"content"`
+	Source             types.String `tfsdk:"source"`
+	SourceHash         types.String `tfsdk:"source_hash"`
+	ContentType        types.String `tfsdk:"content_type"`
+	CacheControl       types.String `tfsdk:"cache_control"`
+	ContentEncoding    types.String `tfsdk:"content_encoding"`
+	ContentDisposition types.String `tfsdk:"content_disposition"`
+	Expires            types.String `tfsdk:"expires"`
+	WebsiteRedirect    types.String `tfsdk:"website_redirect"`
+	ETag               types.String `tfsdk:"etag"`
+	VersionID          types.String `tfsdk:"version_id"`
+	PartSize           types.Int64  `tfsdk:"part_size"`
+	ContentSHA256      types.String `tfsdk:"content_sha256"`
+	SSE                types.String `tfsdk:"server_side_encryption"`
+	KMSKeyID           types.String `tfsdk:"kms_key_id"`
+	SSECustomerKey     types.String `tfsdk:"sse_customer_key"`
+	SSECustomerKeyMD5  types.String `tfsdk:"sse_customer_key_md5"`
+}
+
+func (r *BucketObjectResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_object"
+}
+
+func (r *BucketObjectResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Object in a bucket of Ceph RGW, e.g. an asset of a static website. Any change uploads the object again. Objects can be encrypted by the gateway (SSE-S3, SSE-KMS) or with a customer key (SSE-C). Large objects are uploaded in parts, the MD5 checksum of every part is verified by the gateway and the ETag of the object is verified after the upload.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"key": schema.StringAttribute{
+				MarkdownDescription: "Object key",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"content": schema.StringAttribute{
+				MarkdownDescription: "Content of the object. Conflicts with `source`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("source")),
+				},
+			},
+			"source": schema.StringAttribute{
+				MarkdownDescription: "Path of a file to upload as the object. Conflicts with `content`.",
+				Optional:            true,
+			},
+			"source_hash": schema.StringAttribute{
+				MarkdownDescription: "Arbitrary hash of the `source` file, e.g. `filemd5(path)`, which uploads the object again when changed. The provider never hashes the file itself during plan, so changes of the file are only detected through this attribute.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("source")),
+				},
+			},
+			"content_type": schema.StringAttribute{
+				MarkdownDescription: "MIME type of the object, e.g. `text/html`. Defaults to the content type chosen by the gateway.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"cache_control": schema.StringAttribute{
+				MarkdownDescription: "`Cache-Control` header of the object, e.g. `max-age=3600`",
+				Optional:            true,
+			},
+			"content_encoding": schema.StringAttribute{
+				MarkdownDescription: "`Content-Encoding` header of the object, e.g. `gzip`",
+				Optional:            true,
+			},
+			"content_disposition": schema.StringAttribute{
+				MarkdownDescription: "`Content-Disposition` header of the object, e.g. `attachment`",
+				Optional:            true,
+			},
+			"expires": schema.StringAttribute{
+				MarkdownDescription: "`Expires` header of the object (RFC 3339)",
+				Optional:            true,
+			},
+			"website_redirect": schema.StringAttribute{
+				MarkdownDescription: "URL or absolute path to redirect requests for the object to, if the bucket is served as a static website",
+				Optional:            true,
+			},
+			"part_size": schema.Int64Attribute{
+				MarkdownDescription: "Size of the parts in bytes. Objects larger than a part are uploaded with a multipart upload. Defaults to `16777216` (16 MiB).",
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(defaultPartSize),
+				Validators: []validator.Int64{
+					int64validator.Between(minPartSize, maxPartSize),
+				},
+			},
+			"server_side_encryption": schema.StringAttribute{
+				MarkdownDescription: "Server-side encryption of the object with keys managed by the gateway, `AES256` (SSE-S3) or `aws:kms` (SSE-KMS). Required by buckets whose policy denies unencrypted uploads. Defaults to the encryption applied by the gateway, e.g. the default encryption of the bucket. Conflicts with `sse_customer_key`.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+				Validators: []validator.String{
+					stringvalidator.OneOf(string(s3types.ServerSideEncryptionAes256), string(s3types.ServerSideEncryptionAwsKms)),
+					stringvalidator.ConflictsWith(path.MatchRoot("sse_customer_key")),
+				},
+			},
+			"kms_key_id": schema.StringAttribute{
+				MarkdownDescription: "ID of the key in the KMS backend of the gateway (`rgw_crypt_s3_kms_backend`) to encrypt the object with, if `server_side_encryption` is `aws:kms`. Defaults to the key chosen by the gateway.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"sse_customer_key": schema.StringAttribute{
+				MarkdownDescription: "Base64 encoded 256 bit key to encrypt the object with (SSE-C), e.g. from `openssl rand -base64 32`. The key is only sent with the requests for this object and never read back from the gateway, changes upload the object again. It is kept in the state as sensitive value, as the state is the only place to take it from for reading the object, so protect the state accordingly. The gateway only accepts keys over TLS unless `rgw_crypt_require_ssl` is disabled. Conflicts with `server_side_encryption` and `kms_key_id`.",
+				Optional:            true,
+				Sensitive:           true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("kms_key_id")),
+				},
+			},
+			"sse_customer_key_md5": schema.StringAttribute{
+				MarkdownDescription: "Base64 encoded MD5 digest of the `sse_customer_key` as reported by the gateway, null for objects not encrypted with a customer key",
+				Computed:            true,
+			},
+			"content_sha256": schema.StringAttribute{
+				MarkdownDescription: "SHA256 checksum (hex) of the uploaded content, stored in the `sha256` metadata of the object. Null for objects uploaded without the provider.",
+				Computed:            true,
+			},
+			"etag": schema.StringAttribute{
+				MarkdownDescription: "ETag of the object",
+				Computed:            true,
+			},
+			"version_id": schema.StringAttribute{
+				MarkdownDescription: "Version of the object if versioning is enabled for the bucket",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *BucketObjectResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data BucketObjectResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Expires.IsNull() && !data.Expires.IsUnknown() {
+		if _, err := time.Parse(time.RFC3339, data.Expires.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("expires"), "invalid expires", fmt.Sprintf("expires must be formatted as RFC 3339: %s", err.Error()))
+		}
+	}
+
+	if !data.KMSKeyID.IsNull() && !data.KMSKeyID.IsUnknown() && !data.SSE.IsUnknown() && data.SSE.ValueString() != string(s3types.ServerSideEncryptionAwsKms) {
+		resp.Diagnostics.AddAttributeError(path.Root("kms_key_id"), "invalid kms_key_id", "kms_key_id requires server_side_encryption to be aws:kms")
+	}
+
+	if !data.SSECustomerKey.IsNull() && !data.SSECustomerKey.IsUnknown() {
+		if _, err := sseCustomerKeyMD5(data.SSECustomerKey.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("sse_customer_key"), "invalid sse_customer_key", err.Error())
+		}
+	}
+}
+
+func (r *BucketObjectResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_object")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.client = client
+}
+
+// isNotFound reports whether err signals that an object does not exist.
+func isNotFound(err error) bool {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		switch ae.ErrorCode() {
+		case "NotFound", "NoSuchKey", "404":
+			return true
+		}
+	}
+	return isNoSuchBucket(err)
+}
+
+// optionalString returns a pointer to the value of s, nil if it is null or unknown.
+func optionalString(s types.String) *string {
+	if s.IsNull() || s.IsUnknown() {
+		return nil
+	}
+	return aws.String(s.ValueString())
+}
+
+// stringOrNull returns the value of s, null if s is nil or empty.
+func stringOrNull(s *string) types.String {
+	if aws.StringValue(s) == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(*s)
+}
+
+// sseCustomerKeyMD5 returns the base64 encoded MD5 digest of a base64 encoded SSE-C key,
+// which the gateway requires along with the key.
+func sseCustomerKeyMD5(key string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("sse_customer_key must be base64 encoded: %w", err)
+	}
+	if len(raw) != 32 {
+		return "", fmt.Errorf("sse_customer_key must be a 256 bit key, got %d bits", len(raw)*8)
+	}
+	sum := md5.Sum(raw)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// sseCustomerHeaders returns the algorithm, key and key digest headers of SSE-C requests,
+// all nil if the object is not encrypted with a customer key.
+func sseCustomerHeaders(key types.String) (*string, *string, *string, error) {
+	if key.IsNull() || key.IsUnknown() {
+		return nil, nil, nil, nil
+	}
+	sum, err := sseCustomerKeyMD5(key.ValueString())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return aws.String(string(s3types.ServerSideEncryptionAes256)), aws.String(key.ValueString()), aws.String(sum), nil
+}
+
+// putObject uploads the object with its content and headers.
+func (r *BucketObjectResource) putObject(ctx context.Context, data *BucketObjectResourceModel) error {
+	var body io.ReadSeeker
+	if !data.Source.IsNull() {
+		f, err := os.Open(data.Source.ValueString())
+		if err != nil {
+			return fmt.Errorf("could not open source: %w", err)
+		}
+		defer f.Close()
+		body = f
+	} else {
+		body = strings.NewReader(data.Content.ValueString())
+	}
+
+	checksum, err := contentSHA256(body)
+	if err != nil {
+		return fmt.Errorf("could not read source: %w", err)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:                  aws.String(data.Bucket.ValueString()),
+		Key:                     aws.String(data.Key.ValueString()),
+		Metadata:                map[string]string{sha256MetadataKey: checksum},
+		ContentType:             optionalString(data.ContentType),
+		CacheControl:            optionalString(data.CacheControl),
+		ContentEncoding:         optionalString(data.ContentEncoding),
+		ContentDisposition:      optionalString(data.ContentDisposition),
+		WebsiteRedirectLocation: optionalString(data.WebsiteRedirect),
+	}
+	if !data.Expires.IsNull() {
+		expires, err := time.Parse(time.RFC3339, data.Expires.ValueString())
+		if err != nil {
+			return fmt.Errorf("invalid expires: %w", err)
+		}
+		input.Expires = &expires
+	}
+	if !data.SSE.IsNull() && !data.SSE.IsUnknown() {
+		input.ServerSideEncryption = s3types.ServerSideEncryption(data.SSE.ValueString())
+		input.SSEKMSKeyId = optionalString(data.KMSKeyID)
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5, err = sseCustomerHeaders(data.SSECustomerKey)
+	if err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("put object %s to bucket %s", data.Key.ValueString(), data.Bucket.ValueString()))
+
+	out, err := uploadObject(ctx, r.client.S3, input, body, data.PartSize.ValueInt64())
+	if err != nil {
+		return err
+	}
+
+	data.ETag = types.StringValue(out.ETag)
+	data.VersionID = stringOrNull(out.VersionID)
+	data.ContentSHA256 = types.StringValue(checksum)
+	return nil
+}
+
+// readObject updates the headers of the object from the gateway.
+func (r *BucketObjectResource) readObject(ctx context.Context, data *BucketObjectResourceModel) error {
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+		Key:    aws.String(data.Key.ValueString()),
+	}
+
+	// objects encrypted with a customer key can only be read with the key
+	var err error
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5, err = sseCustomerHeaders(data.SSECustomerKey)
+	if err != nil {
+		return err
+	}
+
+	out, err := r.client.S3.HeadObject(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	data.ContentType = types.StringValue(aws.StringValue(out.ContentType))
+	data.CacheControl = stringOrNull(out.CacheControl)
+	data.ContentEncoding = stringOrNull(out.ContentEncoding)
+	data.ContentDisposition = stringOrNull(out.ContentDisposition)
+	data.WebsiteRedirect = stringOrNull(out.WebsiteRedirectLocation)
+	data.ETag = types.StringValue(strings.Trim(aws.StringValue(out.ETag), `"`))
+	data.VersionID = stringOrNull(out.VersionId)
+	data.SSE = types.StringNull()
+	if out.ServerSideEncryption != "" {
+		data.SSE = types.StringValue(string(out.ServerSideEncryption))
+	}
+	data.KMSKeyID = stringOrNull(out.SSEKMSKeyId)
+	data.SSECustomerKeyMD5 = stringOrNull(out.SSECustomerKeyMD5)
+	if checksum, ok := out.Metadata[sha256MetadataKey]; ok {
+		data.ContentSHA256 = types.StringValue(checksum)
+	} else {
+		data.ContentSHA256 = types.StringNull()
+	}
+
+	// keep the configured formatting if the time is equal
+	if out.Expires == nil {
+		data.Expires = types.StringNull()
+	} else if expires, err := time.Parse(time.RFC3339, data.Expires.ValueString()); err != nil || !expires.Equal(*out.Expires) {
+		data.Expires = types.StringValue(out.Expires.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func (r *BucketObjectResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketObjectResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.putObject(ctx, data); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not put object", err)...)
+		return
+	}
+
+	// the gateway chooses the content type if not configured
+	if err := r.readObject(ctx, data); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not head object", err)...)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s/%s", data.Bucket.ValueString(), data.Key.ValueString()))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketObjectResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *BucketObjectResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.readObject(ctx, data); err != nil {
+		if isNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not head object", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketObjectResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketObjectResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// S3 has no way to modify an object in place, upload it again
+	if err := r.putObject(ctx, data); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not put object", err)...)
+		return
+	}
+
+	if err := r.readObject(ctx, data); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not head object", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketObjectResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *BucketObjectResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := r.client.S3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+		Key:    aws.String(data.Key.ValueString()),
+	})
+	if err != nil && !isNotFound(err) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete object", err)...)
+		return
+	}
+}
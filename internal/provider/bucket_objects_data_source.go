@@ -0,0 +1,269 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// defaultListPageSize is the maximum number of keys the gateway returns per list request.
+const defaultListPageSize = 1000
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &BucketObjectsDataSource{}
+
+func NewBucketObjectsDataSource() datasource.DataSource {
+	return &BucketObjectsDataSource{}
+}
+
+type BucketObjectsDataSource struct {
+	client *RgwClient
+}
+
+type BucketObjectsDataSourceModel struct {
+	Bucket   types.String `tfsdk:"bucket"`
+	Prefix   types.String `tfsdk:"prefix"`
+	PageSize types.Int64  `tfsdk:"page_size"`
+	MaxItems types.Int64  `tfsdk:"max_items"`
+	Keys     types.List   `tfsdk:"keys"`
+
+	IncludeMetadata types.Bool               `tfsdk:"include_metadata"`
+	Objects         []BucketObjectsItemModel `tfsdk:"objects"`
+}
+
+type BucketObjectsItemModel struct {
+	Key          types.String `tfsdk:"key"`
+	Size         types.Int64  `tfsdk:"size"`
+	ETag         types.String `tfsdk:"etag"`
+	StorageClass types.String `tfsdk:"storage_class"`
+	LastModified types.String `tfsdk:"last_modified"`
+	Owner        types.String `tfsdk:"owner"`
+}
+
+func (d *BucketObjectsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_objects"
+}
+
+func (d *BucketObjectsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Keys and metadata of the objects in a bucket, e.g. to find large or stale objects for cleanup",
+
+		Attributes: map[string]schema.Attribute{
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+			},
+			"prefix": schema.StringAttribute{
+				MarkdownDescription: "Only list keys starting with the prefix",
+				Optional:            true,
+			},
+			"page_size": schema.Int64Attribute{
+				MarkdownDescription: "Number of keys requested per list request. Smaller pages reduce the load on the bucket index of the gateway. Defaults to `1000`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, defaultListPageSize),
+				},
+			},
+			"max_items": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of keys to return. Defaults to all keys.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"keys": schema.ListAttribute{
+				MarkdownDescription: "Object keys in lexicographical order",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"include_metadata": schema.BoolAttribute{
+				MarkdownDescription: "Return the metadata of each object in `objects`. Set to `false` to only return `keys`, which keeps the state of large listings small and skips looking up the object owners. Defaults to `true`.",
+				Optional:            true,
+			},
+			"objects": schema.ListNestedAttribute{
+				MarkdownDescription: "Objects in lexicographical order of their keys, null if `include_metadata` is `false`",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"key": schema.StringAttribute{
+							MarkdownDescription: "Object key",
+							Computed:            true,
+						},
+						"size": schema.Int64Attribute{
+							MarkdownDescription: "Size of the object in bytes",
+							Computed:            true,
+						},
+						"etag": schema.StringAttribute{
+							MarkdownDescription: "ETag of the object without quotes",
+							Computed:            true,
+						},
+						"storage_class": schema.StringAttribute{
+							MarkdownDescription: "Storage class of the object",
+							Computed:            true,
+						},
+						"last_modified": schema.StringAttribute{
+							MarkdownDescription: "Time of the last modification of the object (RFC3339)",
+							Computed:            true,
+						},
+						"owner": schema.StringAttribute{
+							MarkdownDescription: "UID of the owner of the object",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *BucketObjectsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the data source can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_objects")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.client = client
+}
+
+// listObjects lists the objects of a bucket in pages of pageSize keys, stopping after maxItems objects if maxItems > 0.
+// The owners of the objects are only returned if fetchOwner is set, which is slower on the gateway.
+func listObjects(ctx context.Context, client *s3.Client, bucket, prefix string, pageSize, maxItems int32, fetchOwner bool) ([]s3types.Object, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket:     aws.String(bucket),
+		MaxKeys:    pageSize,
+		FetchOwner: fetchOwner,
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	var objects []s3types.Object
+	paginator := s3.NewListObjectsV2Paginator(client, input, func(o *s3.ListObjectsV2PaginatorOptions) {
+		o.Limit = pageSize
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			objects = append(objects, obj)
+			if maxItems > 0 && int32(len(objects)) >= maxItems {
+				return objects, nil
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+// listObjectKeys lists the keys of a bucket like listObjects.
+func listObjectKeys(ctx context.Context, client *s3.Client, bucket, prefix string, pageSize, maxItems int32) ([]string, error) {
+	objects, err := listObjects(ctx, client, bucket, prefix, pageSize, maxItems, false)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(objects))
+	for i, obj := range objects {
+		keys[i] = aws.StringValue(obj.Key)
+	}
+	return keys, nil
+}
+
+// objectItemFromS3 returns the attributes of an object returned by a list request.
+func objectItemFromS3(obj s3types.Object) BucketObjectsItemModel {
+	item := BucketObjectsItemModel{
+		Key:          types.StringValue(aws.StringValue(obj.Key)),
+		Size:         types.Int64Value(obj.Size),
+		ETag:         types.StringValue(strings.Trim(aws.StringValue(obj.ETag), `"`)),
+		StorageClass: types.StringValue(string(obj.StorageClass)),
+		LastModified: types.StringNull(),
+		Owner:        types.StringNull(),
+	}
+	if obj.LastModified != nil {
+		item.LastModified = types.StringValue(obj.LastModified.UTC().Format(time.RFC3339))
+	}
+	if obj.Owner != nil && obj.Owner.ID != nil {
+		item.Owner = types.StringValue(aws.StringValue(obj.Owner.ID))
+	}
+	return item
+}
+
+func (d *BucketObjectsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data BucketObjectsDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	pageSize := int32(defaultListPageSize)
+	if !data.PageSize.IsNull() {
+		pageSize = int32(data.PageSize.ValueInt64())
+	}
+	var maxItems int32
+	if !data.MaxItems.IsNull() {
+		maxItems = int32(data.MaxItems.ValueInt64())
+	}
+
+	// never request more keys than needed
+	if maxItems > 0 && maxItems < pageSize {
+		pageSize = maxItems
+	}
+
+	includeMetadata := data.IncludeMetadata.IsNull() || data.IncludeMetadata.ValueBool()
+	objects, err := listObjects(ctx, d.client.S3, data.Bucket.ValueString(), data.Prefix.ValueString(), pageSize, maxItems, includeMetadata)
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(d.client, "could not list objects", err)...)
+		return
+	}
+
+	keys := make([]string, len(objects))
+	for i, obj := range objects {
+		keys[i] = aws.StringValue(obj.Key)
+	}
+	if includeMetadata {
+		data.Objects = make([]BucketObjectsItemModel, len(objects))
+		for i, obj := range objects {
+			data.Objects[i] = objectItemFromS3(obj)
+		}
+	}
+
+	list, diags := types.ListValueFrom(ctx, types.StringType, keys)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Keys = list
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,415 @@
+package provider
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// maxDeleteObjects is the maximum number of keys of a DeleteObjects request.
+const maxDeleteObjects = 1000
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &BucketObjectsSyncResource{}
+var _ resource.ResourceWithModifyPlan = &BucketObjectsSyncResource{}
+
+func NewBucketObjectsSyncResource() resource.Resource {
+	return &BucketObjectsSyncResource{}
+}
+
+type BucketObjectsSyncResource struct {
+	client *RgwClient
+}
+
+type BucketObjectsSyncResourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	Bucket        types.String `tfsdk:"bucket"`
+	Prefix        types.String `tfsdk:"prefix"`
+	SourceDir     types.String `tfsdk:"source_dir"`
+	DeleteRemoved types.Bool   `tfsdk:"delete_removed"`
+	Files         types.Map    `tfsdk:"files"`
+}
+
+func (r *BucketObjectsSyncResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_objects_sync"
+}
+
+func (r *BucketObjectsSyncResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Syncs a local directory tree into a bucket, e.g. to deploy a static website. The files are hashed during plan and only new or changed files are uploaded. Objects deleted outside of Terraform are uploaded again. Upon deletion, all synced objects are deleted.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"prefix": schema.StringAttribute{
+				MarkdownDescription: "Prefix prepended to the relative path of every file, e.g. `site/`. Defaults to the bucket root.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"source_dir": schema.StringAttribute{
+				MarkdownDescription: "Path of the local directory to sync",
+				Required:            true,
+			},
+			"delete_removed": schema.BoolAttribute{
+				MarkdownDescription: "Delete the objects of files removed from `source_dir`. Otherwise the objects are kept in the bucket but no longer managed. Defaults to `false`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"files": schema.MapAttribute{
+				MarkdownDescription: "MD5 checksums (hex) of the synced files by object key",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *BucketObjectsSyncResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_objects_sync")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.client = client
+}
+
+func (r *BucketObjectsSyncResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data BucketObjectsSyncResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.SourceDir.IsUnknown() || data.Prefix.IsUnknown() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("files"), types.MapUnknown(types.StringType))...)
+		return
+	}
+
+	// hash the directory so changed files show up as a diff of files
+	files, err := hashDirectory(data.SourceDir.ValueString(), data.Prefix.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source_dir"), "could not read source directory", err.Error())
+		return
+	}
+
+	filesValue, diags := types.MapValueFrom(ctx, types.StringType, files)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("files"), filesValue)...)
+}
+
+// hashDirectory returns the md5 checksums of all files below dir by their object key.
+func hashDirectory(dir, prefix string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		sum, err := fileMD5(p)
+		if err != nil {
+			return err
+		}
+		files[prefix+filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	return files, err
+}
+
+// fileMD5 returns the hex encoded md5 checksum of a file.
+func fileMD5(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// uploadFile uploads a file of the source directory as object key.
+func (r *BucketObjectsSyncResource) uploadFile(ctx context.Context, data *BucketObjectsSyncResourceModel, key string) error {
+	rel := key[len(data.Prefix.ValueString()):]
+	f, err := os.Open(filepath.Join(data.SourceDir.ValueString(), filepath.FromSlash(rel)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(data.Bucket.ValueString()),
+		Key:    aws.String(key),
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(rel)); contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("put object %s to bucket %s", key, data.Bucket.ValueString()))
+	_, err = uploadObject(ctx, r.client.S3, input, f, defaultPartSize)
+	return err
+}
+
+// deleteObjects deletes the objects with the given keys in batches.
+func deleteObjects(ctx context.Context, client *s3.Client, bucket string, keys []string) error {
+	for start := 0; start < len(keys); start += maxDeleteObjects {
+		end := min(start+maxDeleteObjects, len(keys))
+
+		objects := make([]s3types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, s3types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		out, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3types.Delete{Objects: objects, Quiet: true},
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Errors) > 0 {
+			return fmt.Errorf("could not delete %d objects, first error for %s: %s", len(out.Errors), aws.StringValue(out.Errors[0].Key), aws.StringValue(out.Errors[0].Message))
+		}
+	}
+	return nil
+}
+
+// sync uploads new and changed files and removes files which no longer exist from the state.
+// Objects of removed files are deleted if delete_removed is set.
+func (r *BucketObjectsSyncResource) sync(ctx context.Context, data *BucketObjectsSyncResourceModel, synced map[string]string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	files, err := hashDirectory(data.SourceDir.ValueString(), data.Prefix.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("source_dir"), "could not read source directory", err.Error())
+		return diags
+	}
+
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if synced[key] == files[key] {
+			continue
+		}
+		if err := r.uploadFile(ctx, data, key); err != nil {
+			diags.Append(errorDiagnostics(r.client, "could not put object", fmt.Errorf("could not upload %s: %w", key, err))...)
+			return diags
+		}
+	}
+
+	if data.DeleteRemoved.ValueBool() {
+		var removed []string
+		for key := range synced {
+			if _, ok := files[key]; !ok {
+				removed = append(removed, key)
+			}
+		}
+		if err := deleteObjects(ctx, r.client.S3, data.Bucket.ValueString(), removed); err != nil {
+			diags.Append(errorDiagnostics(r.client, "could not delete removed objects", err)...)
+			return diags
+		}
+	}
+
+	filesValue, d := types.MapValueFrom(ctx, types.StringType, files)
+	diags.Append(d...)
+	data.Files = filesValue
+	return diags
+}
+
+func (r *BucketObjectsSyncResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketObjectsSyncResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.sync(ctx, data, nil)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue(data.Bucket.ValueString() + "/" + data.Prefix.ValueString())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketObjectsSyncResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *BucketObjectsSyncResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var synced map[string]string
+	resp.Diagnostics.Append(data.Files.ElementsAs(ctx, &synced, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	keys, err := listObjectKeys(ctx, r.client.S3, data.Bucket.ValueString(), data.Prefix.ValueString(), defaultListPageSize, 0)
+	if err != nil {
+		if isNoSuchBucket(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not list objects", err)...)
+		return
+	}
+
+	// forget objects deleted outside of terraform, so they are uploaded again
+	existing := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		existing[key] = true
+	}
+	for key := range synced {
+		if !existing[key] {
+			delete(synced, key)
+		}
+	}
+
+	filesValue, diags := types.MapValueFrom(ctx, types.StringType, synced)
+	resp.Diagnostics.Append(diags...)
+	data.Files = filesValue
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketObjectsSyncResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketObjectsSyncResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var dataState *BucketObjectsSyncResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &dataState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var synced map[string]string
+	resp.Diagnostics.Append(dataState.Files.ElementsAs(ctx, &synced, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.sync(ctx, data, synced)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketObjectsSyncResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *BucketObjectsSyncResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var synced map[string]string
+	resp.Diagnostics.Append(data.Files.ElementsAs(ctx, &synced, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	keys := make([]string, 0, len(synced))
+	for key := range synced {
+		keys = append(keys, key)
+	}
+
+	err := deleteObjects(ctx, r.client.S3, data.Bucket.ValueString(), keys)
+	if err != nil && !isNoSuchBucket(err) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete objects", err)...)
+		return
+	}
+}
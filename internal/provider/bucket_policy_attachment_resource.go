@@ -0,0 +1,324 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &BucketPolicyAttachmentResource{}
+var _ resource.ResourceWithValidateConfig = &BucketPolicyAttachmentResource{}
+var _ resource.ResourceWithModifyPlan = &BucketPolicyAttachmentResource{}
+
+func NewBucketPolicyAttachmentResource() resource.Resource {
+	return &BucketPolicyAttachmentResource{}
+}
+
+type BucketPolicyAttachmentResource struct {
+	client *RgwClient
+}
+
+type BucketPolicyAttachmentResourceModel struct {
+	Id         types.String `tfsdk:"id"`
+	Bucket     types.String `tfsdk:"bucket"`
+	Statements types.String `tfsdk:"statements"`
+
+	ValidatePrincipals types.Bool `tfsdk:"validate_principals"`
+}
+
+func (r *BucketPolicyAttachmentResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_policy_attachment"
+}
+
+func (r *BucketPolicyAttachmentResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Statements merged into the policy of a bucket by their `Sid`, so multiple configurations can contribute to the same bucket policy. Statements with other Sids are left untouched. Upon deletion, the statements are removed and the policy is deleted if no statements remain. Do not combine with `rgw_bucket_policy` or the `policy` attribute of `rgw_bucket` for the same bucket.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"statements": schema.StringAttribute{
+				MarkdownDescription: "JSON list of policy statements. Each statement requires a `Sid` unique within the bucket policy.",
+				Required:            true,
+			},
+			"validate_principals": schema.BoolAttribute{
+				MarkdownDescription: "Check at plan time whether the users and roles referenced as principals exist and warn about unknown principals, which grant nothing and usually hint at a typo. Roles can only be checked in the tenant of the provider user. Defaults to `false`.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (r *BucketPolicyAttachmentResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data BucketPolicyAttachmentResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Statements.IsUnknown() || data.Statements.IsNull() {
+		return
+	}
+	if _, _, err := parseAttachmentStatements(data.Statements.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("statements"), "invalid statements", err.Error())
+	}
+}
+
+func (r *BucketPolicyAttachmentResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var statements types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("statements"), &statements)...)
+	if resp.Diagnostics.HasError() || statements.IsUnknown() || statements.IsNull() {
+		return
+	}
+	list, _, err := parseAttachmentStatements(statements.ValueString())
+	if err != nil {
+		return
+	}
+
+	// the statements of other attachments are not known, so the statements are checked on their own
+	policy, err := (&policyDocument{Statements: list}).String()
+	if err != nil {
+		return
+	}
+	resp.Diagnostics.Append(validatePolicyLimits(r.client, path.Root("statements"), policy)...)
+
+	var validatePrincipals types.Bool
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("validate_principals"), &validatePrincipals)...)
+	if validatePrincipals.ValueBool() {
+		resp.Diagnostics.Append(validatePolicyPrincipals(ctx, r.client, path.Root("statements"), list)...)
+	}
+}
+
+func (r *BucketPolicyAttachmentResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_policy_attachment")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.client = client
+}
+
+// parseAttachmentStatements decodes the statements of the attachment and returns them with their Sids.
+func parseAttachmentStatements(statements string) ([]map[string]interface{}, []string, error) {
+	var list []map[string]interface{}
+	if err := json.Unmarshal([]byte(statements), &list); err != nil {
+		return nil, nil, fmt.Errorf("statements must be a JSON list of objects: %w", err)
+	}
+	if len(list) == 0 {
+		return nil, nil, fmt.Errorf("at least one statement is required")
+	}
+
+	sids := make([]string, 0, len(list))
+	for i, st := range list {
+		sid := statementSid(st)
+		if sid == "" {
+			return nil, nil, fmt.Errorf("statement %d has no Sid", i)
+		}
+		if containsString(sids, sid) {
+			return nil, nil, fmt.Errorf("duplicate Sid '%s'", sid)
+		}
+		sids = append(sids, sid)
+	}
+
+	return list, sids, nil
+}
+
+// attachStatements replaces the statements with the given Sids by the new statements.
+func (r *BucketPolicyAttachmentResource) attachStatements(ctx context.Context, bucket string, owned []string, statements string) error {
+	list, sids, err := parseAttachmentStatements(statements)
+	if err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("attach statements %v to policy of bucket %s", sids, bucket))
+
+	return attachPolicyStatements(ctx, r.client, bucket, owned, list)
+}
+
+func (r *BucketPolicyAttachmentResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketPolicyAttachmentResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.attachStatements(ctx, data.Bucket.ValueString(), nil, data.Statements.ValueString()); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not attach bucket policy statements", err)...)
+		return
+	}
+
+	// use bucket name as resource id
+	data.Id = types.StringValue(data.Bucket.ValueString())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketPolicyAttachmentResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *BucketPolicyAttachmentResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, sids, err := parseAttachmentStatements(data.Statements.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("invalid statements in state", err.Error())
+		return
+	}
+
+	policy, err := getBucketPolicy(ctx, r.client.S3, data.Bucket.ValueString())
+	if err != nil {
+		// bucket was deleted outside of terraform
+		if isNoSuchBucket(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket policy", err)...)
+		return
+	}
+
+	doc, err := parsePolicyDocument(policy)
+	if err != nil {
+		resp.Diagnostics.AddError("could not parse bucket policy", err.Error())
+		return
+	}
+
+	// collect the attached statements in the configured order
+	var found []map[string]interface{}
+	for _, sid := range sids {
+		if st, ok := doc.statement(sid); ok {
+			found = append(found, st)
+		}
+	}
+	if len(found) == 0 {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	statements, err := json.Marshal(found)
+	if err != nil {
+		resp.Diagnostics.AddError("could not encode statements", err.Error())
+		return
+	}
+
+	// keep the configured formatting if the statements are semantically equal
+	if !policyEquivalent(string(statements), data.Statements.ValueString()) {
+		data.Statements = types.StringValue(string(statements))
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketPolicyAttachmentResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketPolicyAttachmentResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var dataState *BucketPolicyAttachmentResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &dataState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, owned, err := parseAttachmentStatements(dataState.Statements.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("invalid statements in state", err.Error())
+		return
+	}
+
+	if err := r.attachStatements(ctx, data.Bucket.ValueString(), owned, data.Statements.ValueString()); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify bucket policy statements", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketPolicyAttachmentResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *BucketPolicyAttachmentResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, sids, err := parseAttachmentStatements(data.Statements.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("invalid statements in state", err.Error())
+		return
+	}
+
+	err = modifyBucketPolicy(ctx, r.client, data.Bucket.ValueString(), func(doc *policyDocument) error {
+		doc.removeStatements(sids)
+		return nil
+	})
+	if err != nil && !isNoSuchBucket(err) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not detach bucket policy statements", err)...)
+		return
+	}
+}
@@ -4,19 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.ResourceWithConfigure = &BucketPolicyResource{}
+var _ resource.ResourceWithModifyPlan = &BucketPolicyResource{}
 
 func NewBucketPolicyResource() resource.Resource {
 	return &BucketPolicyResource{}
@@ -29,7 +34,30 @@ type BucketPolicyResource struct {
 type BucketPolicyResourceModel struct {
 	Id     types.String `tfsdk:"id"`
 	Bucket types.String `tfsdk:"bucket"`
+	Tenant types.String `tfsdk:"tenant"`
 	Policy types.String `tfsdk:"policy"`
+
+	ValidatePrincipals types.Bool `tfsdk:"validate_principals"`
+
+	WaitForPropagation types.Bool  `tfsdk:"wait_for_propagation"`
+	PropagationTimeout types.Int64 `tfsdk:"propagation_timeout"`
+}
+
+// s3Bucket returns the bucket name as addressed via s3.
+func (data *BucketPolicyResourceModel) s3Bucket() string {
+	return tenantedS3Bucket(data.Tenant.ValueString(), data.Bucket.ValueString())
+}
+
+// waitForPropagation waits until the gateways serve the policy if wait_for_propagation is set.
+func (r *BucketPolicyResource) waitForPropagation(ctx context.Context, data *BucketPolicyResourceModel, policy string) error {
+	if !data.WaitForPropagation.ValueBool() {
+		return nil
+	}
+	timeout := int64(defaultPolicyPropagationTimeout)
+	if !data.PropagationTimeout.IsNull() {
+		timeout = data.PropagationTimeout.ValueInt64()
+	}
+	return waitForBucketPolicy(ctx, r.client.S3, data.s3Bucket(), policy, time.Duration(timeout)*time.Second)
 }
 
 func (r *BucketPolicyResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -54,14 +82,55 @@ func (r *BucketPolicyResource) Schema(ctx context.Context, req resource.SchemaRe
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"tenant": schema.StringAttribute{
+				MarkdownDescription: "The tenant of the bucket. If set, the bucket is addressed as `tenant:bucket`.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
 			"policy": schema.StringAttribute{
 				MarkdownDescription: "Bucket Policy",
 				Required:            true,
 			},
+			"validate_principals": schema.BoolAttribute{
+				MarkdownDescription: "Check at plan time whether the users and roles referenced as principals exist and warn about unknown principals, which grant nothing and usually hint at a typo. Roles can only be checked in the tenant of the provider user. Defaults to `false`.",
+				Optional:            true,
+			},
+			"wait_for_propagation": schema.BoolAttribute{
+				MarkdownDescription: "Read the policy back after each change until several consecutive reads return it, for setups with multiple gateways which briefly serve stale policies. Defaults to `false`.",
+				Optional:            true,
+			},
+			"propagation_timeout": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Seconds to wait for the policy to propagate with `wait_for_propagation`. Defaults to `%d`.", defaultPolicyPropagationTimeout),
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
 		},
 	}
 }
 
+func (r *BucketPolicyResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	modifyPolicyLimitsPlan(ctx, r.client, "policy", req, resp)
+	if resp.Diagnostics.HasError() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data BucketPolicyResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || !data.ValidatePrincipals.ValueBool() || data.Policy.IsUnknown() {
+		return
+	}
+	// invalid documents are rejected by the gateway
+	doc, err := parsePolicyDocument(data.Policy.ValueString())
+	if err != nil {
+		return
+	}
+	resp.Diagnostics.Append(validatePolicyPrincipals(ctx, r.client, path.Root("policy"), doc.Statements)...)
+}
+
 func (r *BucketPolicyResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	// Prevent panic if the provider has not been configured.
 	if req.ProviderData == nil {
@@ -79,10 +148,21 @@ func (r *BucketPolicyResource) Configure(ctx context.Context, req resource.Confi
 		return
 	}
 
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket_policy")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	r.client = client
 }
 
 func (r *BucketPolicyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *BucketPolicyResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -92,14 +172,34 @@ func (r *BucketPolicyResource) Create(ctx context.Context, req resource.CreateRe
 
 	// Configure PutBucketPolicy
 	s3req := &s3.PutBucketPolicyInput{
-		Bucket: aws.String(data.Bucket.ValueString()),
+		Bucket: aws.String(data.s3Bucket()),
 		Policy: aws.String(data.Policy.ValueString()),
 	}
 
-	// PutBucketPolicy
-	_, err := r.client.S3.PutBucketPolicy(ctx, s3req)
+	// PutBucketPolicy, retry if the bucket was just created and is not yet visible
+	err := retryNoSuchBucket(ctx, func() error {
+		_, err := r.client.S3.PutBucketPolicy(ctx, s3req)
+		return err
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("could not create bucket policy", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create bucket policy", err)...)
+		return
+	}
+
+	// wait until the policy can be read back so the following refresh does not fail
+	err = retryNoSuchBucket(ctx, func() error {
+		_, err := r.client.S3.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+			Bucket: s3req.Bucket,
+		})
+		return err
+	})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket policy", err)...)
+		return
+	}
+
+	if err := r.waitForPropagation(ctx, data, data.Policy.ValueString()); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "bucket policy did not propagate", err)...)
 		return
 	}
 
@@ -120,11 +220,16 @@ func (r *BucketPolicyResource) Read(ctx context.Context, req resource.ReadReques
 
 	// Create GetBucketPolicy Request
 	s3req := &s3.GetBucketPolicyInput{
-		Bucket: aws.String(data.Bucket.ValueString()),
+		Bucket: aws.String(data.s3Bucket()),
 	}
 
 	s3res, err := r.client.S3.GetBucketPolicy(ctx, s3req)
 	if err != nil {
+		// policy or bucket was deleted outside of terraform
+		if isNoSuchBucketPolicy(err) || isNoSuchBucket(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
 			switch ae.ErrorCode() {
@@ -136,17 +241,25 @@ func (r *BucketPolicyResource) Read(ctx context.Context, req resource.ReadReques
 				return
 			}
 		}
-		resp.Diagnostics.AddError("could not get bucket policy", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket policy", err)...)
 		return
 	}
 
-	data.Policy = types.StringValue(*s3res.Policy)
+	// keep the configured formatting if the policy is semantically equal
+	if !policyEquivalent(*s3res.Policy, data.Policy.ValueString()) {
+		data.Policy = types.StringValue(*s3res.Policy)
+	}
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *BucketPolicyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *BucketPolicyResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -156,14 +269,19 @@ func (r *BucketPolicyResource) Update(ctx context.Context, req resource.UpdateRe
 
 	// Configure PutBucketPolicy
 	s3req := &s3.PutBucketPolicyInput{
-		Bucket: aws.String(data.Bucket.ValueString()),
+		Bucket: aws.String(data.s3Bucket()),
 		Policy: aws.String(data.Policy.ValueString()),
 	}
 
 	// PutBucketPolicy
 	_, err := r.client.S3.PutBucketPolicy(ctx, s3req)
 	if err != nil {
-		resp.Diagnostics.AddError("could not modify bucket policy", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify bucket policy", err)...)
+		return
+	}
+
+	if err := r.waitForPropagation(ctx, data, data.Policy.ValueString()); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "bucket policy did not propagate", err)...)
 		return
 	}
 
@@ -172,6 +290,11 @@ func (r *BucketPolicyResource) Update(ctx context.Context, req resource.UpdateRe
 }
 
 func (r *BucketPolicyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform prior state data into the model
 	var data *BucketPolicyResourceModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -180,12 +303,18 @@ func (r *BucketPolicyResource) Delete(ctx context.Context, req resource.DeleteRe
 	}
 
 	s3req := &s3.DeleteBucketPolicyInput{
-		Bucket: aws.String(data.Bucket.ValueString()),
+		Bucket: aws.String(data.s3Bucket()),
 	}
 
 	_, err := r.client.S3.DeleteBucketPolicy(ctx, s3req)
 	if err != nil {
-		resp.Diagnostics.AddError("could not delete bucket policy", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete bucket policy", err)...)
+		return
+	}
+
+	// wait until no gateway serves the removed policy anymore
+	if err := r.waitForPropagation(ctx, data, ""); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "bucket policy removal did not propagate", err)...)
 		return
 	}
 }
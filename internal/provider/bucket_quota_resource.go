@@ -8,10 +8,6 @@ import (
 	"github.com/ceph/go-ceph/rgw/admin"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -19,6 +15,7 @@ import (
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.ResourceWithConfigure = &BucketQuotaResource{}
+var _ resource.ResourceWithModifyPlan = &BucketQuotaResource{}
 
 func NewBucketQuotaResource() resource.Resource {
 	return &BucketQuotaResource{}
@@ -29,13 +26,20 @@ type BucketQuotaResource struct {
 }
 
 type BucketQuotaResourceModel struct {
-	Bucket     types.String `tfsdk:"bucket"`
-	UID        types.String `tfsdk:"uid"`
-	Enabled    types.Bool   `tfsdk:"enabled"`
-	CheckOnRaw types.Bool   `tfsdk:"check_on_raw"`
-	MaxSize    types.Int64  `tfsdk:"max_size"`
-	MaxSizeKB  types.Int64  `tfsdk:"max_size_kb"`
-	MaxObjects types.Int64  `tfsdk:"max_objects"`
+	Bucket       types.String `tfsdk:"bucket"`
+	BucketID     types.String `tfsdk:"bucket_id"`
+	BucketMarker types.String `tfsdk:"bucket_marker"`
+	UID          types.String `tfsdk:"uid"`
+	Tenant       types.String `tfsdk:"tenant"`
+	Enabled      types.Bool   `tfsdk:"enabled"`
+	CheckOnRaw   types.Bool   `tfsdk:"check_on_raw"`
+	MaxSize      types.Int64  `tfsdk:"max_size"`
+	MaxSizeKB    types.Int64  `tfsdk:"max_size_kb"`
+	MaxSizeBytes types.Int64  `tfsdk:"max_size_bytes"`
+	MaxObjects   types.Int64  `tfsdk:"max_objects"`
+	Preserve     types.Bool   `tfsdk:"preserve_on_destroy"`
+	UsedSize     types.Int64  `tfsdk:"used_size_bytes"`
+	UsedObjects  types.Int64  `tfsdk:"used_objects"`
 }
 
 func (r *BucketQuotaResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -43,66 +47,53 @@ func (r *BucketQuotaResource) Metadata(ctx context.Context, req resource.Metadat
 }
 
 func (r *BucketQuotaResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
-	resp.Schema = schema.Schema{
-		MarkdownDescription: "This resource can be used to set individual quota for bucket. Refer to the Ceph RGW Admin Ops API documentation for values documentation. Upon deletion, quota is disabled.",
-
-		Attributes: map[string]schema.Attribute{
-			"bucket": schema.StringAttribute{
-				MarkdownDescription: "The name of the bucket set the quota for.",
-				Required:            true,
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
-			},
-			"uid": schema.StringAttribute{
-				MarkdownDescription: "The UID of the user to set the quota for.",
-				Required:            true,
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
-			},
-			"enabled": schema.BoolAttribute{
-				MarkdownDescription: "Enable or disable the quota",
-				Optional:            true,
-				Computed:            true,
-				Default:             booldefault.StaticBool(true),
-				PlanModifiers: []planmodifier.Bool{
-					boolplanmodifier.UseStateForUnknown(),
-				},
-			},
-			"check_on_raw": schema.BoolAttribute{
-				MarkdownDescription: "???",
-				Optional:            true,
-				Computed:            true,
-				Default:             booldefault.StaticBool(false),
-				PlanModifiers: []planmodifier.Bool{
-					boolplanmodifier.UseStateForUnknown(),
-				},
-			},
-			"max_size": schema.Int64Attribute{
-				MarkdownDescription: "The maximum size of the quota",
-				Computed:            true,
-			},
-			"max_size_kb": schema.Int64Attribute{
-				MarkdownDescription: "The maximum size of the quota in kilobytes",
-				Optional:            true,
-				Computed:            true,
-				Default:             int64default.StaticInt64(0),
-				PlanModifiers: []planmodifier.Int64{
-					int64planmodifier.UseStateForUnknown(),
-				},
-			},
-			"max_objects": schema.Int64Attribute{
-				MarkdownDescription: "The maximum number of objects in the quota",
-				Optional:            true,
-				Computed:            true,
-				Default:             int64default.StaticInt64(-1),
-				PlanModifiers: []planmodifier.Int64{
-					int64planmodifier.UseStateForUnknown(),
-				},
-			},
+	attributes := quotaLimitAttributes()
+	attributes["bucket"] = schema.StringAttribute{
+		MarkdownDescription: "The name of the bucket set the quota for.",
+		Required:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+	attributes["bucket_id"] = schema.StringAttribute{
+		MarkdownDescription: "The id of the bucket instance the quota is set on. Changes when the bucket is resharded.",
+		Computed:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.UseStateForUnknown(),
+		},
+	}
+	attributes["bucket_marker"] = schema.StringAttribute{
+		MarkdownDescription: "The marker of the bucket the quota is set on, which is kept when the bucket is resharded. A bucket with the same name but a different marker, e.g. after the bucket was deleted and created again, is reported with a warning on refresh and the quota is set again on the new bucket.",
+		Computed:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.UseStateForUnknown(),
+		},
+	}
+	attributes["uid"] = schema.StringAttribute{
+		MarkdownDescription: "The UID of the user to set the quota for.",
+		Required:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
 		},
 	}
+	attributes["tenant"] = schema.StringAttribute{
+		MarkdownDescription: "The tenant of the user and bucket. If set, `uid` is qualified as `tenant$uid` and `bucket` as `tenant/bucket` for all api calls.",
+		Optional:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+
+	quotaUsageAttributes(attributes, "the bucket")
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "This resource can be used to set individual quota for bucket. Refer to the Ceph RGW Admin Ops API documentation for values documentation. Upon deletion, quota is disabled and its limits are reset unless `preserve_on_destroy` is set.",
+		Attributes:          attributes,
+	}
+}
+
+func (r *BucketQuotaResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	modifyQuotaSizePlan(ctx, req, resp)
 }
 
 func (r *BucketQuotaResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
@@ -126,32 +117,34 @@ func (r *BucketQuotaResource) Configure(ctx context.Context, req resource.Config
 }
 
 func rgwBucketQuotaFromSchemaQuota(data *BucketQuotaResourceModel) admin.QuotaSpec {
-	enabled := data.Enabled.ValueBool()
 	quota := admin.QuotaSpec{
-		Bucket:     data.Bucket.ValueString(),
-		UID:        data.UID.ValueString(),
-		Enabled:    &enabled,
-		CheckOnRaw: data.CheckOnRaw.ValueBool(),
+		Bucket: tenantedBucket(data.Tenant.ValueString(), data.Bucket.ValueString()),
+		UID:    tenantedUID(data.Tenant.ValueString(), data.UID.ValueString()),
 	}
+	setQuotaLimits(&quota, data.Enabled, data.CheckOnRaw, data.MaxSize, data.MaxObjects)
 
-	// treat 0 as max_size quote disabled
-	if !data.MaxSizeKB.IsNull() && data.MaxSizeKB.ValueInt64() != 0 {
-		maxSizeKb := int(data.MaxSizeKB.ValueInt64())
-		quota.MaxSizeKb = &maxSizeKb
-	} else {
-		maxSize := int64(-1)
-		quota.MaxSize = &maxSize
-	}
+	return quota
+}
 
-	if !data.MaxObjects.IsNull() {
-		maxObjects := data.MaxObjects.ValueInt64()
-		quota.MaxObjects = &maxObjects
+// isQuotaBucket reports whether bucket is the bucket the quota was set on. The bucket id
+// changes when the bucket is resharded while the marker is kept, so the marker identifies
+// the bucket. States without marker only have the id to compare with.
+func isQuotaBucket(bucket rgwBucketInfo, bucketID, marker types.String) bool {
+	if !marker.IsNull() && !marker.IsUnknown() {
+		return bucket.Marker == marker.ValueString()
 	}
-
-	return quota
+	if !bucketID.IsNull() && !bucketID.IsUnknown() {
+		return bucket.ID == bucketID.ValueString() || bucket.Marker == bucketID.ValueString()
+	}
+	return true
 }
 
 func (r *BucketQuotaResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *BucketQuotaResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -163,15 +156,18 @@ func (r *BucketQuotaResource) Create(ctx context.Context, req resource.CreateReq
 	err := r.client.Admin.SetIndividualBucketQuota(ctx, quota)
 
 	if err != nil {
-		resp.Diagnostics.AddError("could not create bucket quota", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create bucket quota", err)...)
 		return
 	}
 
-	if data.MaxSizeKB.ValueInt64() != 0 {
-		data.MaxSize = types.Int64Value(data.MaxSizeKB.ValueInt64() * 1024)
-	} else {
-		data.MaxSize = types.Int64Value(-1)
+	bucket, err := getBucketInfo(ctx, r.client.Admin, tenantedBucket(data.Tenant.ValueString(), data.Bucket.ValueString()))
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket usage", err)...)
+		return
 	}
+	data.BucketID = types.StringValue(bucket.ID)
+	data.BucketMarker = types.StringValue(bucket.Marker)
+	quotaUsageFromStats(bucketUsageStats(bucket.Bucket), data.CheckOnRaw.ValueBool(), &data.UsedSize, &data.UsedObjects)
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -185,13 +181,8 @@ func (r *BucketQuotaResource) Read(ctx context.Context, req resource.ReadRequest
 		return
 	}
 
-	// prepare request attributes
-	reqBucket := admin.Bucket{
-		Bucket: data.Bucket.ValueString(),
-	}
-
-	// get bucket quota
-	bucket, err := r.client.Admin.GetBucketInfo(ctx, reqBucket)
+	// get bucket quota and usage
+	bucket, err := getBucketInfo(ctx, r.client.Admin, tenantedBucket(data.Tenant.ValueString(), data.Bucket.ValueString()))
 
 	if err != nil {
 		if errors.Is(err, admin.ErrNoSuchBucket) {
@@ -199,29 +190,36 @@ func (r *BucketQuotaResource) Read(ctx context.Context, req resource.ReadRequest
 			resp.State.RemoveResource(ctx)
 			return
 		}
-		resp.Diagnostics.AddError("could not get bucket quota", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket quota", err)...)
 		return
 	}
 
-	if bucket.BucketQuota.Enabled != nil {
-		data.Enabled = types.BoolValue(*bucket.BucketQuota.Enabled)
-	}
-	data.CheckOnRaw = types.BoolValue(bucket.BucketQuota.CheckOnRaw)
-	if bucket.BucketQuota.MaxSize != nil {
-		data.MaxSize = types.Int64Value(*bucket.BucketQuota.MaxSize)
-	}
-	if bucket.BucketQuota.MaxSizeKb != nil {
-		data.MaxSizeKB = types.Int64Value(int64(*bucket.BucketQuota.MaxSizeKb))
-	}
-	if bucket.BucketQuota.MaxObjects != nil {
-		data.MaxObjects = types.Int64Value(*bucket.BucketQuota.MaxObjects)
+	// the name might refer to another bucket by now, whose quota is not managed by this resource
+	if !isQuotaBucket(bucket, data.BucketID, data.BucketMarker) {
+		resp.Diagnostics.AddWarning(
+			"bucket was recreated",
+			fmt.Sprintf("bucket %s has the id %s and marker %s, but the quota was set on the bucket with the id %s. The quota is set again on the current bucket.", bucket.Bucket.Bucket, bucket.ID, bucket.Marker, data.BucketID.ValueString()),
+		)
+		// Remove bucket quota from state
+		resp.State.RemoveResource(ctx)
+		return
 	}
 
+	quotaLimitsFromSpec(bucket.BucketQuota, &data.Enabled, &data.CheckOnRaw, &data.MaxSize, &data.MaxSizeKB, &data.MaxSizeBytes, &data.MaxObjects)
+	data.BucketID = types.StringValue(bucket.ID)
+	data.BucketMarker = types.StringValue(bucket.Marker)
+	quotaUsageFromStats(bucketUsageStats(bucket.Bucket), data.CheckOnRaw.ValueBool(), &data.UsedSize, &data.UsedObjects)
+
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *BucketQuotaResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *BucketQuotaResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -233,20 +231,19 @@ func (r *BucketQuotaResource) Update(ctx context.Context, req resource.UpdateReq
 	err := r.client.Admin.SetIndividualBucketQuota(ctx, quota)
 
 	if err != nil {
-		resp.Diagnostics.AddError("could not modify bucket quota", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify bucket quota", err)...)
 		return
 	}
-	if data.MaxSizeKB.ValueInt64() != 0 {
-		data.MaxSize = types.Int64Value(data.MaxSizeKB.ValueInt64() * 1024)
-	} else {
-		data.MaxSize = types.Int64Value(-1)
-	}
-
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *BucketQuotaResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform prior state data into the model
 	var data *BucketQuotaResourceModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -255,22 +252,12 @@ func (r *BucketQuotaResource) Delete(ctx context.Context, req resource.DeleteReq
 	}
 
 	quota := rgwBucketQuotaFromSchemaQuota(data)
-	f := false
-	quota.Enabled = &f
-	maxSize := int64(-1)
-	quota.MaxSize = &maxSize
-	quota.MaxSizeKb = nil
-	maxObjects := int64(-1)
-	quota.MaxObjects = &maxObjects
+	disableQuota(&quota, data.Preserve.ValueBool())
 
+	// nothing to disable if the bucket or its owner is already gone
 	err := r.client.Admin.SetIndividualBucketQuota(ctx, quota)
-	if err != nil {
-		resp.Diagnostics.AddError("could not modify bucket quota", err.Error())
-		return
-	}
-
-	if err != nil && !errors.Is(err, admin.ErrNoSuchBucket) {
-		resp.Diagnostics.AddError("could not delete bucket quota", err.Error())
+	if err != nil && !errors.Is(err, admin.ErrNoSuchBucket) && !errors.Is(err, admin.ErrNoSuchUser) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete bucket quota", err)...)
 		return
 	}
 }
@@ -0,0 +1,204 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &BucketRateLimitResource{}
+var _ resource.ResourceWithImportState = &BucketRateLimitResource{}
+
+func NewBucketRateLimitResource() resource.Resource {
+	return &BucketRateLimitResource{}
+}
+
+type BucketRateLimitResource struct {
+	client *RgwClient
+}
+
+type BucketRateLimitResourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	Bucket        types.String `tfsdk:"bucket"`
+	Tenant        types.String `tfsdk:"tenant"`
+	Enabled       types.Bool   `tfsdk:"enabled"`
+	MaxReadOps    types.Int64  `tfsdk:"max_read_ops"`
+	MaxWriteOps   types.Int64  `tfsdk:"max_write_ops"`
+	MaxReadBytes  types.Int64  `tfsdk:"max_read_bytes"`
+	MaxWriteBytes types.Int64  `tfsdk:"max_write_bytes"`
+}
+
+// bucket returns the bucket name qualified with the tenant.
+func (data *BucketRateLimitResourceModel) bucket() string {
+	return tenantedBucket(data.Tenant.ValueString(), data.Bucket.ValueString())
+}
+
+func (r *BucketRateLimitResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_rate_limit"
+}
+
+func (r *BucketRateLimitResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	attributes := rateLimitAttributes()
+	attributes["id"] = schema.StringAttribute{
+		Computed: true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.UseStateForUnknown(),
+		},
+	}
+	attributes["bucket"] = schema.StringAttribute{
+		MarkdownDescription: "The name of the bucket to set the rate limit for.",
+		Required:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+	attributes["tenant"] = schema.StringAttribute{
+		MarkdownDescription: "The tenant of the bucket. If set, `bucket` is qualified as `tenant/bucket` for all api calls.",
+		Optional:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "This resource can be used to set the rate limit of a bucket, which applies to the requests of all users to the bucket. Limits modified out-of-band, e.g. with `radosgw-admin ratelimit set`, are detected on refresh and planned back to the configuration. Upon deletion, the rate limit is disabled and its limits are reset. Existing rate limits can be imported by the bucket name, as `tenant/bucket` for buckets of a tenant. Requires Ceph >= quincy.",
+		Attributes:          attributes,
+	}
+}
+
+func (r *BucketRateLimitResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *BucketRateLimitResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	resp.Diagnostics.Append(requireCephRelease(r.client, 17, "rgw_bucket_rate_limit")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketRateLimitResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	limit := rateLimitFromSchema(data.Enabled, data.MaxReadOps, data.MaxWriteOps, data.MaxReadBytes, data.MaxWriteBytes)
+	if err := setRateLimit(ctx, r.client, "bucket", "", data.bucket(), limit); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create bucket rate limit", err)...)
+		return
+	}
+	data.Id = types.StringValue(data.bucket())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketRateLimitResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *BucketRateLimitResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	limit, err := getRateLimit(ctx, r.client, "bucket", "", data.bucket())
+	if err != nil {
+		if errors.Is(err, admin.ErrNoSuchBucket) {
+			// Remove rate limit from state
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket rate limit", err)...)
+		return
+	}
+
+	// always take the limits of the gateway, so out-of-band changes show up as drift
+	rateLimitToSchema(limit, &data.Enabled, &data.MaxReadOps, &data.MaxWriteOps, &data.MaxReadBytes, &data.MaxWriteBytes)
+	data.Id = types.StringValue(data.bucket())
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketRateLimitResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *BucketRateLimitResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	limit := rateLimitFromSchema(data.Enabled, data.MaxReadOps, data.MaxWriteOps, data.MaxReadBytes, data.MaxWriteBytes)
+	if err := setRateLimit(ctx, r.client, "bucket", "", data.bucket(), limit); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify bucket rate limit", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BucketRateLimitResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *BucketRateLimitResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// nothing to disable if the bucket is already gone
+	err := setRateLimit(ctx, r.client, "bucket", "", data.bucket(), rgwRateLimit{})
+	if err != nil && !errors.Is(err, admin.ErrNoSuchBucket) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete bucket rate limit", err)...)
+		return
+	}
+}
+
+func (r *BucketRateLimitResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// the rate limit is imported by the bucket name, optionally qualified as "tenant/bucket"
+	bucket := req.ID
+	if tenant, name, ok := strings.Cut(req.ID, "/"); ok {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("tenant"), tenant)...)
+		bucket = name
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("bucket"), bucket)...)
+}
@@ -4,20 +4,32 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/smithy-go"
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/boolvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.ResourceWithConfigure = &BucketResource{}
+var _ resource.ResourceWithModifyPlan = &BucketResource{}
 
 func NewBucketResource() resource.Resource {
 	return &BucketResource{}
@@ -28,8 +40,32 @@ type BucketResource struct {
 }
 
 type BucketResourceModel struct {
-	Id   types.String `tfsdk:"id"`
-	Name types.String `tfsdk:"name"`
+	Id           types.String `tfsdk:"id"`
+	Name         types.String `tfsdk:"name"`
+	Owner        types.String `tfsdk:"owner"`
+	NumObjects   types.Int64  `tfsdk:"num_objects"`
+	SizeActual   types.Int64  `tfsdk:"size_actual"`
+	SizeUtilized types.Int64  `tfsdk:"size_utilized"`
+	NumShards    types.Int64  `tfsdk:"num_shards"`
+	CreationTime types.String `tfsdk:"creation_time"`
+	Tags         types.Map    `tfsdk:"tags"`
+	TagsAll      types.Map    `tfsdk:"tags_all"`
+	Versioning   types.Bool   `tfsdk:"versioning_enabled"`
+	MFADelete    types.Bool   `tfsdk:"mfa_delete_enabled"`
+	MFA          types.String `tfsdk:"mfa"`
+	Policy       types.String `tfsdk:"policy"`
+	Placement    types.String `tfsdk:"placement_rule"`
+	StorageClass types.String `tfsdk:"storage_class"`
+	ForceDestroy types.Bool   `tfsdk:"force_destroy"`
+	Adopt        types.Bool   `tfsdk:"adopt_existing"`
+	AsOwner      types.Bool   `tfsdk:"create_as_owner"`
+	ObjectLock   types.Bool   `tfsdk:"object_lock_enabled"`
+	BucketID     types.String `tfsdk:"bucket_id"`
+	EndpointURL  types.String `tfsdk:"endpoint_url"`
+	VirtualHost  types.String `tfsdk:"virtual_host_url"`
+	IndexType    types.String `tfsdk:"index_type"`
+	Zonegroup    types.String `tfsdk:"zonegroup"`
+	Compression  types.String `tfsdk:"compression_type"`
 }
 
 func (r *BucketResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -55,6 +91,163 @@ func (r *BucketResource) Schema(ctx context.Context, req resource.SchemaRequest,
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"owner": schema.StringAttribute{
+				MarkdownDescription: "The UID of the user owning the bucket (`tenant$uid` for tenanted users). The bucket is linked to this user right after creation, or created as this user with `create_as_owner`. Defaults to the user the provider is configured with. A bucket relinked outside of this resource, e.g. by `rgw_bucket_link`, is reported with a warning on refresh and linked back to the configured owner on apply.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"num_objects": schema.Int64Attribute{
+				MarkdownDescription: "Number of objects in the bucket",
+				Computed:            true,
+			},
+			"size_actual": schema.Int64Attribute{
+				MarkdownDescription: "Actual size of the bucket in bytes (including allocation overhead)",
+				Computed:            true,
+			},
+			"size_utilized": schema.Int64Attribute{
+				MarkdownDescription: "Utilized size of the bucket in bytes (after compression)",
+				Computed:            true,
+			},
+			"num_shards": schema.Int64Attribute{
+				MarkdownDescription: "Number of bucket index shards",
+				Computed:            true,
+			},
+			"index_type": schema.StringAttribute{
+				MarkdownDescription: "Bucket index type, e.g. `Normal` or `Indexless`. The index type is defined by the placement target the bucket is created in.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"zonegroup": schema.StringAttribute{
+				MarkdownDescription: "ID of the zonegroup the bucket is placed in. Together with `placement_rule` and `storage_class` this describes the full placement of the bucket.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"compression_type": schema.StringAttribute{
+				MarkdownDescription: "Compression configured for the placement target and storage class of the bucket in the zone, e.g. `zlib`. Empty if objects are not compressed, null if the zone configuration is not available.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"creation_time": schema.StringAttribute{
+				MarkdownDescription: "Creation time of the bucket",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"tags": schema.MapAttribute{
+				MarkdownDescription: "Tags to set on the bucket. These are merged with the provider `default_tags`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"tags_all": schema.MapAttribute{
+				MarkdownDescription: "All tags set on the bucket, including the provider `default_tags`",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"versioning_enabled": schema.BoolAttribute{
+				MarkdownDescription: "Enable or suspend versioning on the bucket. If not set, the versioning state is not managed but reported.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"mfa_delete_enabled": schema.BoolAttribute{
+				MarkdownDescription: "Require multi-factor authentication to delete object versions or change the versioning state. Requires `versioning_enabled` and `mfa` to be changed. If not set, the MFA delete state is not managed but reported.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"mfa": schema.StringAttribute{
+				MarkdownDescription: "Serial number and current token of the MFA device separated by a space, e.g. `serial 123456`. Only sent to the gateway when `versioning_enabled` or `mfa_delete_enabled` change, a new token alone does not show up in the plan. It is never read back from the gateway, but kept in the state as sensitive value of the last change, so protect the state accordingly.",
+				Optional:            true,
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"bucket_id": schema.StringAttribute{
+				MarkdownDescription: "The bucket instance id",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"endpoint_url": schema.StringAttribute{
+				MarkdownDescription: "Path-style URL of the bucket built from the provider endpoint",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"virtual_host_url": schema.StringAttribute{
+				MarkdownDescription: "Virtual-host-style URL of the bucket built from the provider endpoint",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"placement_rule": schema.StringAttribute{
+				MarkdownDescription: "The placement target the bucket is created in. Defaults to the default placement of the zonegroup.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"storage_class": schema.StringAttribute{
+				MarkdownDescription: "The default storage class of the placement target for objects in the bucket, e.g. `COLD`. Defaults to `STANDARD`.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"force_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Delete all objects in the bucket when the bucket is destroyed. Objects are not recoverable.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"adopt_existing": schema.BoolAttribute{
+				MarkdownDescription: "Adopt the bucket into the state if it already exists and is owned by the user of the provider, e.g. after a partially failed apply, instead of failing. The configured tags, versioning and policy are applied to the adopted bucket.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"create_as_owner": schema.BoolAttribute{
+				MarkdownDescription: "Create the bucket with the S3 key of `owner`, which is looked up with the admin API, instead of creating it as the user of the provider and linking it to `owner` afterwards. The bucket is owned by `owner` from the start, e.g. for gateways which restrict the buckets of the provider user. All S3 requests to the bucket are signed with the key of `owner` afterwards as well, so `owner` must keep an S3 key. Defaults to `false`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+				Validators: []validator.Bool{
+					boolvalidator.AlsoRequires(path.MatchRoot("owner")),
+				},
+			},
+			"object_lock_enabled": schema.BoolAttribute{
+				MarkdownDescription: "Enable object lock for the bucket. This also enables versioning and can only be set on creation. Use `rgw_bucket_object_lock` to configure a default retention.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"policy": schema.StringAttribute{
+				MarkdownDescription: "Bucket Policy (JSON). The policy is removed when the attribute is unset. Do not combine with `rgw_bucket_policy` for the same bucket.",
+				Optional:            true,
+			},
 		},
 	}
 }
@@ -76,10 +269,163 @@ func (r *BucketResource) Configure(ctx context.Context, req resource.ConfigureRe
 		return
 	}
 
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_bucket")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	r.client = client
 }
 
+func (r *BucketResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var defaultTags map[string]string
+	if r.client != nil {
+		defaultTags = r.client.DefaultTags
+	}
+	modifyTagsAllPlan(ctx, defaultTags, req, resp)
+	modifyPolicyLimitsPlan(ctx, r.client, "policy", req, resp)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the versioning state can only be changed with mfa once mfa delete is enabled
+	var mfaDelete, stateMFADelete, stateVersioning, versioning types.Bool
+	var mfa types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("mfa_delete_enabled"), &mfaDelete)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("versioning_enabled"), &versioning)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("mfa"), &mfa)...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("mfa_delete_enabled"), &stateMFADelete)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("versioning_enabled"), &stateVersioning)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	mfaDeleteChanged := !mfaDelete.IsUnknown() && mfaDelete.ValueBool() != stateMFADelete.ValueBool()
+	versioningChanged := !versioning.IsUnknown() && versioning.ValueBool() != stateVersioning.ValueBool()
+	if mfa.IsNull() && (mfaDeleteChanged || (versioningChanged && stateMFADelete.ValueBool())) {
+		resp.Diagnostics.AddAttributeError(path.Root("mfa"), "mfa required", "mfa must be set to change mfa_delete_enabled or the versioning of a bucket with mfa delete enabled")
+	}
+
+	// the token of the mfa device changes all the time, but is only used to change the
+	// versioning, so a new token alone does not update the bucket
+	if !req.State.Raw.IsNull() && !mfaDelete.IsUnknown() && !versioning.IsUnknown() && !mfaDeleteChanged && !versioningChanged {
+		var stateMFA types.String
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("mfa"), &stateMFA)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("mfa"), stateMFA)...)
+	} else {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("mfa"), mfa)...)
+	}
+	if mfaDelete.ValueBool() && !versioning.IsUnknown() && !versioning.ValueBool() {
+		resp.Diagnostics.AddAttributeError(path.Root("mfa_delete_enabled"), "versioning required", "mfa delete can only be enabled for buckets with versioning enabled")
+	}
+
+	// validate placement of new buckets
+	if req.State.Raw.IsNull() && r.client != nil {
+		var data *BucketResourceModel
+		resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(r.validatePlacement(ctx, data)...)
+	}
+}
+
+// validatePlacement checks the configured placement target and storage class against the zonegroup map.
+func (r *BucketResource) validatePlacement(ctx context.Context, data *BucketResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	placement := data.Placement.ValueString()
+	storageClass := data.StorageClass.ValueString()
+	if placement == "" && storageClass == "" {
+		return diags
+	}
+
+	zonegroupMap, err := getZonegroupMap(ctx, r.client.Admin)
+	if err != nil {
+		diags.AddWarning("could not validate bucket placement", fmt.Sprintf("could not get zonegroup map: %s", err.Error()))
+		return diags
+	}
+
+	if placement == "" {
+		placement = zonegroupMap.defaultPlacement()
+	}
+
+	target, ok := zonegroupMap.placementTarget(placement)
+	if !ok {
+		diags.AddAttributeError(path.Root("placement_rule"), "unknown placement target", fmt.Sprintf("placement target '%s' does not exist in any zonegroup, available: %s", placement, strings.Join(zonegroupMap.placementTargetNames(), ", ")))
+		return diags
+	}
+
+	if storageClass != "" {
+		for _, sc := range target.StorageClasses {
+			if sc == storageClass {
+				return diags
+			}
+		}
+		diags.AddAttributeError(path.Root("storage_class"), "unknown storage class", fmt.Sprintf("storage class '%s' is not available in placement target '%s', available: %v", storageClass, placement, target.StorageClasses))
+	}
+
+	return diags
+}
+
+// bucketS3Client returns the s3 client for requests to the bucket: the client of the
+// provider, or a client signing with the key of the owner if the bucket is created as
+// the owner, as the provider user might not have access to the buckets of the owner.
+func (r *BucketResource) bucketS3Client(ctx context.Context, data *BucketResourceModel) (*s3.Client, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if !data.AsOwner.ValueBool() {
+		return r.client.S3, diags
+	}
+	if data.Owner.IsNull() || data.Owner.IsUnknown() {
+		diags.AddAttributeError(path.Root("owner"), "owner required", "create_as_owner requires a known owner")
+		return nil, diags
+	}
+	client, err := r.ownerS3Client(ctx, data.Owner.ValueString())
+	if err != nil {
+		diags.Append(errorDiagnostics(r.client, "could not get s3 key of owner", err)...)
+		return nil, diags
+	}
+	return client, diags
+}
+
+// ownerS3Client returns an s3 client signing requests with the s3 key of the user.
+func (r *BucketResource) ownerS3Client(ctx context.Context, uid string) (*s3.Client, error) {
+	user, err := r.client.Admin.GetUser(ctx, admin.User{ID: uid})
+	if err != nil {
+		return nil, err
+	}
+
+	// keys of subusers are listed as "uid:subuser"
+	for _, key := range user.Keys {
+		if key.User != uid {
+			continue
+		}
+		accessKey, secretKey := key.AccessKey, key.SecretKey
+		options := r.client.S3Options
+		options.Credentials = awsv2.CredentialsProviderFunc(func(ctx context.Context) (awsv2.Credentials, error) {
+			return awsv2.Credentials{
+				AccessKeyID:     accessKey,
+				SecretAccessKey: secretKey,
+			}, nil
+		})
+		return s3.New(options), nil
+	}
+	return nil, fmt.Errorf("user %s has no s3 key", uid)
+}
+
 func (r *BucketResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *BucketResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -87,21 +433,113 @@ func (r *BucketResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
+	// the bucket is set up with the key of the owner if it is created as the owner
+	s3client, diags := r.bucketS3Client(ctx, data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Configure CreateBucketInput
 	s3req := &s3.CreateBucketInput{
 		Bucket: aws.String(data.Name.ValueString()),
 	}
+	if data.ObjectLock.ValueBool() {
+		s3req.ObjectLockEnabledForBucket = true
+	}
+	if constraint := locationConstraint(data); constraint != "" {
+		s3req.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraint(constraint),
+		}
+	}
 
 	tflog.Info(ctx, fmt.Sprintf("create bucket %s", *s3req.Bucket))
 
-	_, err := r.client.S3.CreateBucket(ctx, s3req)
-	if err != nil {
-		resp.Diagnostics.AddError("could not create bucket", err.Error())
-		return
+	_, err := s3client.CreateBucket(ctx, s3req)
+	if err == nil {
+		// remove the new bucket again if it can not be set up below, it would exist on the
+		// gateway without being tracked otherwise and the next apply would fail to create it
+		defer func() {
+			if resp.Diagnostics.HasError() {
+				r.removeFailedBucket(ctx, *s3req.Bucket, &resp.Diagnostics)
+			}
+		}()
+	} else {
+		var ae smithy.APIError
+		alreadyOwned := errors.As(err, &ae) && ae.ErrorCode() == "BucketAlreadyOwnedByYou"
+		switch {
+		case alreadyOwned && data.Adopt.ValueBool():
+			tflog.Info(ctx, fmt.Sprintf("adopt existing bucket %s", *s3req.Bucket))
+			resp.Diagnostics.AddWarning("adopted existing bucket", fmt.Sprintf("bucket %s already existed and was adopted into the state", *s3req.Bucket))
+		case alreadyOwned:
+			resp.Diagnostics.AddError("could not create bucket", fmt.Sprintf("bucket %s already exists and is owned by you. Set adopt_existing = true to adopt it.", *s3req.Bucket))
+			return
+		default:
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create bucket", err)...)
+			return
+		}
 	}
 
 	data.Id = types.StringValue(*s3req.Bucket)
 
+	// set bucket tags before the bucket is handed over to its owner
+	allTags, diags := tagsFromMap(ctx, data.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if len(allTags) > 0 {
+		if err := putBucketTags(ctx, s3client, data.Id.ValueString(), allTags); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not set bucket tags", err)...)
+			return
+		}
+	}
+	data.TagsAll = tagsToMap(allTags)
+
+	// enable versioning, a new bucket is always unversioned
+	if data.Versioning.ValueBool() || data.MFADelete.ValueBool() {
+		if err := putBucketVersioning(ctx, s3client, data.Id.ValueString(), true, data.MFADelete.ValueBoolPointer(), data.MFA.ValueString()); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not enable bucket versioning", err)...)
+			return
+		}
+	}
+	data.Versioning = types.BoolValue(data.Versioning.ValueBool())
+	data.MFADelete = types.BoolValue(data.MFADelete.ValueBool())
+
+	// set bucket policy
+	if !data.Policy.IsNull() {
+		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: aws.String(data.Id.ValueString()),
+			Policy: aws.String(data.Policy.ValueString()),
+		})
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create bucket policy", err)...)
+			return
+		}
+	}
+
+	// link bucket to the requested owner
+	if !data.Owner.IsNull() && !data.Owner.IsUnknown() && !data.AsOwner.ValueBool() {
+		tflog.Info(ctx, fmt.Sprintf("link bucket %s to owner %s", *s3req.Bucket, data.Owner.ValueString()))
+		err = r.client.Admin.LinkBucket(ctx, admin.BucketLinkInput{
+			Bucket: data.Id.ValueString(),
+			UID:    data.Owner.ValueString(),
+		})
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not link bucket to owner", err)...)
+			return
+		}
+	}
+
+	// get bucket owner and statistics
+	bucket, err := getBucketInfo(ctx, r.client.Admin, data.Id.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket info", err)...)
+		return
+	}
+	data.setBucketInfo(bucket, r.client.Admin.Endpoint)
+	data.Compression = r.compressionType(ctx, data)
+
 	// Write logs using the tflog package
 	// Documentation: https://terraform.io/plugin/log
 	tflog.Trace(ctx, "created a resource")
@@ -110,6 +548,17 @@ func (r *BucketResource) Create(ctx context.Context, req resource.CreateRequest,
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// removeFailedBucket deletes a bucket which was created but could not be set up. Adopted
+// buckets are never removed. The bucket is removed with the admin api, as it might already
+// be linked to its owner.
+func (r *BucketResource) removeFailedBucket(ctx context.Context, bucket string, diags *diag.Diagnostics) {
+	tflog.Info(ctx, fmt.Sprintf("remove bucket %s which could not be set up", bucket))
+	err := r.client.Admin.RemoveBucket(context.WithoutCancel(ctx), admin.Bucket{Bucket: bucket})
+	if err != nil && !errors.Is(err, admin.ErrNoSuchBucket) {
+		diags.AddWarning("could not remove bucket", fmt.Sprintf("bucket %s was created but could not be set up and could not be removed again: %s. Remove it manually or import it.", bucket, err.Error()))
+	}
+}
+
 func (r *BucketResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
 	// Read Terraform prior state data into the model
 	var data *BucketResourceModel
@@ -118,12 +567,18 @@ func (r *BucketResource) Read(ctx context.Context, req resource.ReadRequest, res
 		return
 	}
 
+	s3client, diags := r.bucketS3Client(ctx, data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Create Head Bucket Request
 	s3req := &s3.HeadBucketInput{
 		Bucket: aws.String(data.Id.ValueString()),
 	}
 
-	_, err := r.client.S3.HeadBucket(ctx, s3req)
+	_, err := s3client.HeadBucket(ctx, s3req)
 	if err != nil {
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
@@ -132,21 +587,88 @@ func (r *BucketResource) Read(ctx context.Context, req resource.ReadRequest, res
 				resp.State.RemoveResource(ctx)
 				return
 			case "403":
-				resp.Diagnostics.AddError("no permission to head bucket", err.Error())
+				resp.Diagnostics.Append(errorDiagnostics(r.client, "no permission to head bucket", err)...)
 				return
 			}
 		}
-		resp.Diagnostics.AddError("could not head bucket", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not head bucket", err)...)
 		return
 	}
 
 	data.Name = types.StringValue(*s3req.Bucket)
 
+	// get bucket owner and statistics, unless skipped for buckets already known, e.g. not just imported
+	if !r.client.SkipRefreshStats || data.BucketID.IsNull() {
+		bucket, err := getBucketInfo(ctx, r.client.Admin, data.Id.ValueString())
+		if err != nil {
+			if errors.Is(err, admin.ErrNoSuchBucket) {
+				resp.State.RemoveResource(ctx)
+				return
+			}
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket info", err)...)
+			return
+		}
+		priorOwner := data.Owner
+		data.setBucketInfo(bucket, r.client.Admin.Endpoint)
+		data.Compression = r.compressionType(ctx, data)
+
+		// the owner is computed if not configured, so a bucket relinked outside of this
+		// resource, e.g. by rgw_bucket_link, would otherwise silently take the new owner
+		if !priorOwner.IsNull() && !priorOwner.IsUnknown() && priorOwner.ValueString() != "" && !priorOwner.Equal(data.Owner) {
+			resp.Diagnostics.AddWarning(
+				"bucket owner changed",
+				fmt.Sprintf("bucket %s is owned by %s instead of %s, it was relinked outside of this resource, e.g. by rgw_bucket_link or radosgw-admin. If owner is configured, the bucket is linked back to %s on apply, otherwise the new owner is kept. Configure the owner only in one place to avoid relinking the bucket on every apply.", data.Id.ValueString(), data.Owner.ValueString(), priorOwner.ValueString(), priorOwner.ValueString()),
+			)
+		}
+	}
+
+	// get bucket tags
+	allTags, err := getBucketTags(ctx, s3client, data.Id.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket tags", err)...)
+		return
+	}
+	data.TagsAll = tagsToMap(allTags)
+	tags := resourceTags(r.client.DefaultTags, allTags)
+	if len(tags) > 0 || !data.Tags.IsNull() {
+		data.Tags = tagsToMap(tags)
+	}
+
+	// get bucket versioning
+	versioning, err := s3client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{
+		Bucket: aws.String(data.Id.ValueString()),
+	})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket versioning", err)...)
+		return
+	}
+	data.Versioning = types.BoolValue(versioning.Status == s3types.BucketVersioningStatusEnabled)
+	data.MFADelete = types.BoolValue(versioning.MFADelete == s3types.MFADeleteStatusEnabled)
+
+	// get bucket policy if managed inline, keep the configured formatting if semantically equal
+	if !data.Policy.IsNull() {
+		policy, err := getBucketPolicy(ctx, s3client, data.Id.ValueString())
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket policy", err)...)
+			return
+		}
+		if policy == "" {
+			data.Policy = types.StringNull()
+		} else if !policyEquivalent(policy, data.Policy.ValueString()) {
+			data.Policy = types.StringValue(policy)
+		}
+	}
+
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *BucketResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *BucketResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -154,13 +676,101 @@ func (r *BucketResource) Update(ctx context.Context, req resource.UpdateRequest,
 		return
 	}
 
-	// Currently there is nothing to update in place
+	var dataState *BucketResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &dataState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the bucket is still owned by the owner of the state until it is relinked below
+	s3client, diags := r.bucketS3Client(ctx, dataState)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// update bucket tags
+	if !data.TagsAll.Equal(dataState.TagsAll) {
+		allTags, diags := tagsFromMap(ctx, data.TagsAll)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if err := putBucketTags(ctx, s3client, data.Id.ValueString(), allTags); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not set bucket tags", err)...)
+			return
+		}
+	}
+
+	// update bucket versioning and mfa delete
+	if data.Versioning.IsUnknown() {
+		data.Versioning = dataState.Versioning
+	}
+	if data.MFADelete.IsUnknown() {
+		data.MFADelete = dataState.MFADelete
+	}
+	if !data.Versioning.Equal(dataState.Versioning) || !data.MFADelete.Equal(dataState.MFADelete) {
+		var mfaDelete *bool
+		if !data.MFADelete.Equal(dataState.MFADelete) {
+			mfaDelete = data.MFADelete.ValueBoolPointer()
+		}
+		if err := putBucketVersioning(ctx, s3client, data.Id.ValueString(), data.Versioning.ValueBool(), mfaDelete, data.MFA.ValueString()); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify bucket versioning", err)...)
+			return
+		}
+	}
+
+	// update bucket policy
+	if !data.Policy.Equal(dataState.Policy) {
+		var err error
+		if data.Policy.IsNull() {
+			_, err = s3client.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
+				Bucket: aws.String(data.Id.ValueString()),
+			})
+		} else {
+			_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+				Bucket: aws.String(data.Id.ValueString()),
+				Policy: aws.String(data.Policy.ValueString()),
+			})
+		}
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify bucket policy", err)...)
+			return
+		}
+	}
+
+	// relink bucket if the owner changed
+	if !data.Owner.IsUnknown() && !data.Owner.Equal(dataState.Owner) {
+		tflog.Info(ctx, fmt.Sprintf("link bucket %s to owner %s", data.Id.ValueString(), data.Owner.ValueString()))
+		err := r.client.Admin.LinkBucket(ctx, admin.BucketLinkInput{
+			Bucket: data.Id.ValueString(),
+			UID:    data.Owner.ValueString(),
+		})
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not link bucket to owner", err)...)
+			return
+		}
+	}
+
+	// refresh bucket owner and statistics
+	bucket, err := getBucketInfo(ctx, r.client.Admin, data.Id.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket info", err)...)
+		return
+	}
+	data.setBucketInfo(bucket, r.client.Admin.Endpoint)
+	data.Compression = r.compressionType(ctx, data)
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *BucketResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var data *BucketResourceModel
 
 	// Read Terraform prior state data into the model
@@ -170,13 +780,148 @@ func (r *BucketResource) Delete(ctx context.Context, req resource.DeleteRequest,
 		return
 	}
 
+	// purge bucket including all objects
+	if data.ForceDestroy.ValueBool() {
+		purge := true
+		err := r.client.Admin.RemoveBucket(ctx, admin.Bucket{
+			Bucket:      data.Id.ValueString(),
+			PurgeObject: &purge,
+		})
+		if err != nil && !errors.Is(err, admin.ErrNoSuchBucket) {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete bucket", err)...)
+		}
+		return
+	}
+
+	// check whether the bucket is empty to give a helpful error
+	bucket, err := getBucketInfo(ctx, r.client.Admin, data.Id.ValueString())
+	if err != nil {
+		if errors.Is(err, admin.ErrNoSuchBucket) {
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket info", err)...)
+		return
+	}
+	if numObjects := valueOrDefault(bucket.Usage.RgwMain.NumObjects, 0); numObjects > 0 {
+		resp.Diagnostics.AddError(
+			"bucket is not empty",
+			fmt.Sprintf("bucket %s still contains %d objects. Delete the objects or set force_destroy = true and apply before destroying the bucket.", data.Id.ValueString(), numObjects),
+		)
+		return
+	}
+
+	s3client, diags := r.bucketS3Client(ctx, data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	s3req := &s3.DeleteBucketInput{
 		Bucket: aws.String(data.Id.ValueString()),
 	}
 
-	_, err := r.client.S3.DeleteBucket(ctx, s3req)
+	_, err = s3client.DeleteBucket(ctx, s3req)
 	if err != nil {
-		resp.Diagnostics.AddError("could not delete bucket", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete bucket", err)...)
 		return
 	}
 }
+
+// locationConstraint builds the RGW location constraint ":<placement>[/<storage class>]" of a bucket.
+func locationConstraint(data *BucketResourceModel) string {
+	placement := data.Placement.ValueString()
+	storageClass := data.StorageClass.ValueString()
+	if placement == "" && storageClass == "" {
+		return ""
+	}
+	if storageClass == "" {
+		return ":" + placement
+	}
+	return fmt.Sprintf(":%s/%s", placement, storageClass)
+}
+
+// compressionType looks up the compression of the bucket placement in the zone configuration.
+// The zone configuration is not available on all gateways, in which case null is returned.
+func (r *BucketResource) compressionType(ctx context.Context, data *BucketResourceModel) types.String {
+	zone, err := getZone(ctx, r.client.Admin)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("could not get zone configuration: %s", err.Error()))
+		return types.StringNull()
+	}
+
+	compression, ok := zone.compressionType(data.Placement.ValueString(), data.StorageClass.ValueString())
+	if !ok {
+		return types.StringNull()
+	}
+	return types.StringValue(compression)
+}
+
+// putBucketVersioning enables or suspends versioning of a bucket. MFA delete is only
+// changed if mfaDelete is set, mfa is the serial and token of the MFA device if required.
+func putBucketVersioning(ctx context.Context, client *s3.Client, bucket string, enabled bool, mfaDelete *bool, mfa string) error {
+	status := s3types.BucketVersioningStatusSuspended
+	if enabled {
+		status = s3types.BucketVersioningStatusEnabled
+	}
+	input := &s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucket),
+		VersioningConfiguration: &s3types.VersioningConfiguration{
+			Status: status,
+		},
+	}
+	if mfaDelete != nil {
+		input.VersioningConfiguration.MFADelete = s3types.MFADeleteDisabled
+		if *mfaDelete {
+			input.VersioningConfiguration.MFADelete = s3types.MFADeleteEnabled
+		}
+	}
+	if mfa != "" {
+		input.MFA = aws.String(mfa)
+	}
+	_, err := client.PutBucketVersioning(ctx, input)
+	return err
+}
+
+// setBucketInfo updates owner, urls and computed statistics from the admin api bucket info.
+func (data *BucketResourceModel) setBucketInfo(bucket rgwBucketInfo, endpoint string) {
+	data.Owner = types.StringValue(bucket.Owner)
+	data.BucketID = types.StringValue(bucket.ID)
+
+	pathStyle, virtualHost := bucketURLs(endpoint, data.Id.ValueString())
+	data.EndpointURL = types.StringValue(pathStyle)
+	data.VirtualHost = types.StringValue(virtualHost)
+
+	usage := bucket.Usage.RgwMain
+	data.NumObjects = types.Int64Value(int64(valueOrDefault(usage.NumObjects, 0)))
+	data.SizeActual = types.Int64Value(int64(valueOrDefault(usage.SizeActual, 0)))
+	data.SizeUtilized = types.Int64Value(int64(valueOrDefault(usage.SizeUtilized, 0)))
+	data.NumShards = types.Int64Value(int64(valueOrDefault(bucket.NumShards, 0)))
+	data.CreationTime = types.StringValue(bucket.CreationTime)
+	data.IndexType = types.StringValue(bucket.IndexType)
+	data.Zonegroup = types.StringValue(bucket.Zonegroup)
+
+	// placement rule is reported as "<placement>[/<storage class>]"
+	placement, storageClass, _ := strings.Cut(bucket.PlacementRule, "/")
+	if storageClass == "" {
+		storageClass = "STANDARD"
+	}
+	data.Placement = types.StringValue(placement)
+	data.StorageClass = types.StringValue(storageClass)
+}
+
+// bucketURLs returns the path-style and virtual-host-style URL of a bucket.
+func bucketURLs(endpoint, bucket string) (string, string) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Sprintf("%s/%s", strings.TrimSuffix(endpoint, "/"), url.PathEscape(bucket)), ""
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	pathStyle := *u
+	pathStyle.Path = fmt.Sprintf("%s/%s", u.Path, bucket)
+
+	virtualHost := *u
+	virtualHost.Host = fmt.Sprintf("%s.%s", bucket, u.Host)
+
+	return pathStyle.String(), virtualHost.String()
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxConsecutiveFailures = 5
+	circuitRetries                = 3
+	circuitRetryDelay             = 500 * time.Millisecond
+	circuitCooldown               = 30 * time.Second
+)
+
+// errCircuitOpen is returned for requests which are rejected without contacting the gateway.
+var errCircuitOpen = errors.New("circuit breaker open")
+
+// circuitBreakerTransport is shared by the admin and s3 clients. Idempotent requests
+// are retried with exponential backoff on connection errors and server errors. After
+// maxFailures consecutive failed requests all requests fail fast until the cooldown
+// passed, so a flapping gateway does not stall every resource of an apply on its own
+// timeouts. A single request is let through after the cooldown to probe the gateway.
+type circuitBreakerTransport struct {
+	next        http.RoundTripper
+	endpoint    string
+	maxFailures int
+
+	mu        sync.Mutex
+	failures  int
+	lastError error
+	openUntil time.Time
+}
+
+func newCircuitBreakerTransport(next http.RoundTripper, endpoint string, maxFailures int) *circuitBreakerTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &circuitBreakerTransport{
+		next:        next,
+		endpoint:    endpoint,
+		maxFailures: maxFailures,
+	}
+}
+
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.allow(); err != nil {
+		return nil, err
+	}
+
+	delay := circuitRetryDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil && req.Context().Err() != nil {
+			// canceled by terraform, not a failure of the gateway
+			return resp, err
+		}
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		t.record(failed, err, resp)
+
+		if !failed || attempt >= circuitRetries || !retryable(req) {
+			return resp, err
+		}
+
+		// close the failed response so the connection can be reused
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if err := t.allow(); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// allow rejects requests while the circuit is open.
+func (t *circuitBreakerTransport) allow() error {
+	if t.maxFailures <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.failures < t.maxFailures {
+		return nil
+	}
+	if time.Now().Before(t.openUntil) {
+		return fmt.Errorf("%w: rgw endpoint %s is unavailable after %d consecutive failed requests, last error: %v", errCircuitOpen, t.endpoint, t.failures, t.lastError)
+	}
+
+	// half-open: let a single probe request through and keep rejecting the others
+	t.openUntil = time.Now().Add(circuitCooldown)
+	return nil
+}
+
+// record tracks the consecutive failures and opens the circuit once maxFailures is reached.
+func (t *circuitBreakerTransport) record(failed bool, err error, resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !failed {
+		t.failures = 0
+		t.lastError = nil
+		return
+	}
+
+	t.failures++
+	if err != nil {
+		t.lastError = err
+	} else {
+		t.lastError = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if t.maxFailures > 0 && t.failures >= t.maxFailures {
+		t.openUntil = time.Now().Add(circuitCooldown)
+	}
+}
+
+// retryable reports whether a request can safely be sent again.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
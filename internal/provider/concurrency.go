@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const defaultConcurrency = 10
+
+// forEachConcurrent calls fn for each key with at most workers calls running at the
+// same time. It returns the errors by key, keys not yet started when ctx is canceled
+// fail with the context error.
+func forEachConcurrent(ctx context.Context, keys []string, workers int, fn func(ctx context.Context, key string) error) map[string]error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	errs := make(map[string]error)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for _, key := range keys {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			errs[key] = ctx.Err()
+			mu.Unlock()
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(ctx, key); err != nil {
+				mu.Lock()
+				errs[key] = err
+				mu.Unlock()
+			}
+		}(key)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// joinKeyErrors formats the errors of forEachConcurrent sorted by key.
+func joinKeyErrors(errs map[string]error) string {
+	keys := make([]string, 0, len(errs))
+	for k := range errs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("%s: %s", k, errs[k].Error()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// keyedMutex serializes callers by key while callers with different keys proceed
+// in parallel. Unused keys are removed, so the map does not grow with every key
+// ever locked.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*keyedLock)}
+}
+
+// lock blocks until the key is available and returns the function to unlock it.
+func (m *keyedMutex) lock(key string) func() {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		m.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// rootCause describes a failure which is not specific to a resource but affects
+// every request to the gateway, like expired credentials.
+type rootCause struct {
+	Key         string
+	Status      int
+	Explanation string
+}
+
+// rootCauseCodes maps the error codes of the gateway to the root causes they signal.
+var rootCauseCodes = map[string]rootCause{
+	"InvalidAccessKeyId":    {"InvalidAccessKeyId", http.StatusForbidden, "the access key of the provider is unknown to the gateway"},
+	"SignatureDoesNotMatch": {"SignatureDoesNotMatch", http.StatusForbidden, "the secret key of the provider does not match the access key"},
+	"RequestTimeTooSkewed":  {"RequestTimeTooSkewed", http.StatusForbidden, "the clock of this host differs too much from the clock of the gateway"},
+	"ExpiredToken":          {"ExpiredToken", http.StatusBadRequest, "the session token of the provider credentials has expired"},
+}
+
+// errorCode returns the error code of an api error of the admin, s3 or v1 sdk clients.
+func errorCode(err error) string {
+	var adminErr adminError
+	if errors.As(err, &adminErr) {
+		return adminErr.Code
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code()
+	}
+	// go-ceph does not export its error type, its message starts with the code
+	if fields := strings.Fields(err.Error()); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// errorRequestID returns the request id the gateway assigned to a failed request, as
+// reported in the x-amz-request-id header or the RequestId of the error body. It is
+// logged by the gateway, e.g. "req 1 ... tx00000...", and empty if the request did not
+// reach the gateway.
+func errorRequestID(err error) string {
+	var adminErr adminError
+	if errors.As(err, &adminErr) {
+		return adminErr.RequestID
+	}
+	var responseErr interface{ ServiceRequestID() string }
+	if errors.As(err, &responseErr) {
+		return responseErr.ServiceRequestID()
+	}
+	var requestErr awserr.RequestFailure
+	if errors.As(err, &requestErr) {
+		return requestErr.RequestID()
+	}
+	// go-ceph does not export its error type, its message is "<code> <request id> <host id>"
+	if fields := strings.Fields(err.Error()); len(fields) == 3 && strings.HasPrefix(fields[1], "tx") {
+		return fields[1]
+	}
+	return ""
+}
+
+// withRequestID appends the request id of err to the detail of a diagnostic.
+func withRequestID(detail string, err error) string {
+	if id := errorRequestID(err); id != "" {
+		return fmt.Sprintf("%s\n\nRequest ID: %s", detail, id)
+	}
+	return detail
+}
+
+// classifyError returns the root cause of err if it is not specific to a resource.
+func classifyError(err error) (rootCause, bool) {
+	if errors.Is(err, errCircuitOpen) {
+		return rootCause{"CircuitOpen", http.StatusServiceUnavailable, "the gateway failed too many consecutive requests"}, true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return rootCause{"ConnectionFailed", 0, fmt.Sprintf("the gateway can not be reached: %s", opErr.Err)}, true
+	}
+	cause, ok := rootCauseCodes[errorCode(err)]
+	return cause, ok
+}
+
+// String returns the status and key of the root cause, e.g. "HTTP 403 InvalidAccessKeyId".
+func (c rootCause) String() string {
+	if c.Status == 0 {
+		return c.Key
+	}
+	return fmt.Sprintf("HTTP %d %s", c.Status, c.Key)
+}
+
+// errorTracker coalesces the errors of a provider instance by their root cause. The first
+// error of a root cause is reported in full, further errors of the same root cause only
+// refer to it, so an apply with expired credentials does not print the same error wall
+// for every resource.
+type errorTracker struct {
+	mu    sync.Mutex
+	count map[string]int
+}
+
+func newErrorTracker() *errorTracker {
+	return &errorTracker{count: map[string]int{}}
+}
+
+// seen records an error of the root cause and returns the number of earlier errors.
+func (t *errorTracker) seen(cause rootCause) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := t.count[cause.Key]
+	t.count[cause.Key]++
+	return n
+}
+
+// errorDiagnostics returns the error diagnostics for a failed request, coalescing
+// errors with the same root cause across all resources of the provider.
+func errorDiagnostics(client *RgwClient, summary string, err error) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	cause, ok := classifyError(err)
+	if !ok || client == nil || client.Errors == nil {
+		diags.AddError(summary, withRequestID(err.Error(), err))
+		return diags
+	}
+
+	earlier := client.Errors.seen(cause)
+	if earlier == 0 {
+		diags.AddError(summary, withRequestID(fmt.Sprintf("%s\n\nRoot cause: %s, %s. This affects all requests to the gateway, errors of other resources with the same root cause are shortened.", err.Error(), cause, cause.Explanation), err))
+	} else {
+		diags.AddError(summary, fmt.Sprintf("%s: same root cause as %d earlier failed request(s), see the first error for details.", cause, earlier))
+	}
+	return diags
+}
@@ -0,0 +1,267 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	logTypeBucketIndex = "bucket-index"
+	logTypeMetadata    = "metadata"
+	logTypeData        = "data"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &LogTrimResource{}
+var _ resource.ResourceWithValidateConfig = &LogTrimResource{}
+
+func NewLogTrimResource() resource.Resource {
+	return &LogTrimResource{}
+}
+
+type LogTrimResource struct {
+	client *RgwClient
+}
+
+type LogTrimResourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	Type          types.String `tfsdk:"type"`
+	Bucket        types.String `tfsdk:"bucket"`
+	ShardID       types.Int64  `tfsdk:"shard_id"`
+	StartMarker   types.String `tfsdk:"start_marker"`
+	EndMarker     types.String `tfsdk:"end_marker"`
+	Triggers      types.Map    `tfsdk:"triggers"`
+	TrimmedShards types.Int64  `tfsdk:"trimmed_shards"`
+}
+
+func (r *LogTrimResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_log_trim"
+}
+
+func (r *LogTrimResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Trims a multisite log of the RGW, like `radosgw-admin bilog|mdlog|datalog trim`. Log entries are removed when the resource is created, any change of its attributes or `triggers` trims again. Destroying the resource does not restore any log entries.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"type": schema.StringAttribute{
+				MarkdownDescription: "The log to trim - can be either `bucket-index` (bilog), `metadata` (mdlog) or `data` (datalog)",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(logTypeBucketIndex, logTypeMetadata, logTypeData),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "The bucket to trim the index log of, required for type `bucket-index`",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"shard_id": schema.Int64Attribute{
+				MarkdownDescription: "The log shard to trim, required for types `metadata` and `data`. Markers are positions within a single shard, so every shard is trimmed with its own resource and marker, e.g. the marker peers have synced of that shard.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"start_marker": schema.StringAttribute{
+				MarkdownDescription: "Trim log entries starting at this marker",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"end_marker": schema.StringAttribute{
+				MarkdownDescription: "Trim log entries up to this marker, required for types `metadata` and `data`, where it is a marker of the shard `shard_id`. If not set, the whole bucket index log is trimmed.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				MarkdownDescription: "Arbitrary values which trim the log again when changed, e.g. a timestamp",
+				ElementType:         types.StringType,
+				Optional:            true,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"trimmed_shards": schema.Int64Attribute{
+				MarkdownDescription: "The number of log shards trimmed by the last trim operation",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *LogTrimResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data LogTrimResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Type.IsUnknown() {
+		return
+	}
+
+	switch data.Type.ValueString() {
+	case logTypeBucketIndex:
+		if data.Bucket.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("bucket"), "bucket required", "trimming the bucket index log requires a bucket")
+		}
+		if !data.ShardID.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("shard_id"), "shard_id not supported", "the bucket index log is trimmed for all shards of the bucket")
+		}
+	case logTypeMetadata, logTypeData:
+		if !data.Bucket.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("bucket"), "bucket not supported", fmt.Sprintf("the %s log is not bucket specific", data.Type.ValueString()))
+		}
+		if data.EndMarker.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("end_marker"), "end_marker required", fmt.Sprintf("trimming the %s log requires an end marker", data.Type.ValueString()))
+		}
+		if data.ShardID.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("shard_id"), "shard_id required", fmt.Sprintf("the end marker is a position within one shard of the %s log, trimming other shards up to it could drop entries peers have not synced yet", data.Type.ValueString()))
+		}
+	}
+}
+
+func (r *LogTrimResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *LogTrimResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *LogTrimResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	logType := data.Type.ValueString()
+	startMarker := data.StartMarker.ValueString()
+	endMarker := data.EndMarker.ValueString()
+
+	if logType == logTypeBucketIndex {
+		tflog.Info(ctx, fmt.Sprintf("trim index log of bucket %s from '%s' until '%s'", data.Bucket.ValueString(), startMarker, endMarker))
+
+		err := trimLog(ctx, r.client.Admin, logType, url.Values{"bucket": {data.Bucket.ValueString()}}, startMarker, endMarker)
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not trim bucket index log", err)...)
+			return
+		}
+
+		data.Id = types.StringValue(fmt.Sprintf("%s:%s:%s", logType, data.Bucket.ValueString(), endMarker))
+		data.TrimmedShards = types.Int64Value(1)
+
+		// Save data into Terraform state
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	// trim the configured shard, the marker is only valid within that shard
+	shard := int(data.ShardID.ValueInt64())
+	numShards, err := getLogShards(ctx, r.client.Admin, logType)
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, fmt.Sprintf("could not get %s log shards", logType), err)...)
+		return
+	}
+	if shard >= numShards {
+		resp.Diagnostics.AddAttributeError(path.Root("shard_id"), "invalid shard_id", fmt.Sprintf("the %s log has %d shards, shard %d does not exist", logType, numShards, shard))
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("trim shard %d of %s log from '%s' until '%s'", shard, logType, startMarker, endMarker))
+
+	err = trimLog(ctx, r.client.Admin, logType, url.Values{"id": {strconv.Itoa(shard)}}, startMarker, endMarker)
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, fmt.Sprintf("could not trim shard %d of %s log", shard, logType), err)...)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s:%d:%s", logType, shard, endMarker))
+	data.TrimmedShards = types.Int64Value(1)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *LogTrimResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Trimming is an action, there is nothing to read back
+}
+
+func (r *LogTrimResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *LogTrimResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// All attributes require replacement, there is nothing to update in place
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *LogTrimResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Trimmed log entries can not be restored, just remove the resource from state
+}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &MetadataDataSource{}
+
+func NewMetadataDataSource() datasource.DataSource {
+	return &MetadataDataSource{}
+}
+
+type MetadataDataSource struct {
+	client *RgwClient
+}
+
+type MetadataDataSourceModel struct {
+	Type types.String `tfsdk:"type"`
+	Key  types.String `tfsdk:"key"`
+	JSON types.String `tfsdk:"json"`
+}
+
+func (d *MetadataDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_metadata"
+}
+
+func (d *MetadataDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Raw metadata entry of the RGW admin API, like `radosgw-admin metadata get`. Intended for advanced automation where no dedicated resource or attribute exists. The format of the document depends on the Ceph release.",
+
+		Attributes: map[string]schema.Attribute{
+			"type": schema.StringAttribute{
+				MarkdownDescription: "Metadata section - can be either `user`, `bucket` or `bucket.instance`",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf("user", "bucket", "bucket.instance"),
+				},
+			},
+			"key": schema.StringAttribute{
+				MarkdownDescription: "Metadata key, e.g. the uid for `user`, the bucket name for `bucket` or `<bucket>:<bucket id>` for `bucket.instance`. Tenanted entries are prefixed with `<tenant>/` for buckets and `<tenant>$` for users.",
+				Required:            true,
+			},
+			"json": schema.StringAttribute{
+				MarkdownDescription: "The metadata entry as JSON document",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *MetadataDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *MetadataDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data MetadataDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body, err := adminCall(ctx, d.client.Admin, http.MethodGet, "/metadata/"+data.Type.ValueString(), url.Values{
+		"key": {data.Key.ValueString()},
+	})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(d.client, fmt.Sprintf("could not get %s metadata %s", data.Type.ValueString(), data.Key.ValueString()), err)...)
+		return
+	}
+
+	data.JSON = types.StringValue(string(body))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	defaultPartSize = 16 << 20
+	minPartSize     = 5 << 20
+	maxPartSize     = 5 << 30
+	maxParts        = 10000
+
+	// sha256MetadataKey is the user metadata key the checksum of the content is stored in.
+	sha256MetadataKey = "sha256"
+)
+
+// uploadResult describes an uploaded object.
+type uploadResult struct {
+	ETag      string
+	VersionID *string
+}
+
+// contentSHA256 returns the hex encoded sha256 checksum of body and rewinds it.
+func contentSHA256(body io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// uploadObject uploads body with the headers of input. Bodies larger than partSize are
+// uploaded in parts. The gateway verifies the MD5 checksum of every request and the
+// ETag of the uploaded object is compared to the checksums of the parts afterwards.
+func uploadObject(ctx context.Context, client *s3.Client, input *s3.PutObjectInput, body io.Reader, partSize int64) (uploadResult, error) {
+	buf := make([]byte, partSize)
+	n, err := io.ReadFull(body, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return uploadResult{}, err
+	}
+
+	// small objects are uploaded with a single request
+	if int64(n) < partSize {
+		sum := md5.Sum(buf[:n])
+		input.Body = bytes.NewReader(buf[:n])
+		input.ContentMD5 = aws.String(base64.StdEncoding.EncodeToString(sum[:]))
+
+		out, err := client.PutObject(ctx, input)
+		if err != nil {
+			return uploadResult{}, err
+		}
+		result := uploadResult{ETag: strings.Trim(aws.StringValue(out.ETag), `"`), VersionID: out.VersionId}
+		return result, verifyETag(result.ETag, hex.EncodeToString(sum[:]))
+	}
+
+	return uploadMultipart(ctx, client, input, body, buf)
+}
+
+// uploadMultipart uploads the object in parts, buf holds the first part.
+func uploadMultipart(ctx context.Context, client *s3.Client, input *s3.PutObjectInput, body io.Reader, buf []byte) (uploadResult, error) {
+	created, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                  input.Bucket,
+		Key:                     input.Key,
+		ContentType:             input.ContentType,
+		CacheControl:            input.CacheControl,
+		ContentEncoding:         input.ContentEncoding,
+		ContentDisposition:      input.ContentDisposition,
+		WebsiteRedirectLocation: input.WebsiteRedirectLocation,
+		Expires:                 input.Expires,
+		Metadata:                input.Metadata,
+		ServerSideEncryption:    input.ServerSideEncryption,
+		SSEKMSKeyId:             input.SSEKMSKeyId,
+		SSECustomerAlgorithm:    input.SSECustomerAlgorithm,
+		SSECustomerKey:          input.SSECustomerKey,
+		SSECustomerKeyMD5:       input.SSECustomerKeyMD5,
+	})
+	if err != nil {
+		return uploadResult{}, fmt.Errorf("could not create multipart upload: %w", err)
+	}
+
+	result, err := uploadParts(ctx, client, input, created.UploadId, body, buf)
+	if err != nil {
+		// do not leave the uploaded parts behind, even if terraform was interrupted
+		_, abortErr := client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   input.Bucket,
+			Key:      input.Key,
+			UploadId: created.UploadId,
+		})
+		if abortErr != nil {
+			tflog.Warn(ctx, fmt.Sprintf("could not abort multipart upload %s: %s", aws.StringValue(created.UploadId), abortErr.Error()))
+		}
+		return uploadResult{}, err
+	}
+
+	return result, nil
+}
+
+// uploadParts uploads the parts of a multipart upload and completes it.
+func uploadParts(ctx context.Context, client *s3.Client, input *s3.PutObjectInput, uploadID *string, body io.Reader, buf []byte) (uploadResult, error) {
+	var parts []s3types.CompletedPart
+	var sums []byte
+
+	n := len(buf)
+	for n > 0 {
+		partNumber := int32(len(parts) + 1)
+		if partNumber > maxParts {
+			return uploadResult{}, fmt.Errorf("object exceeds %d parts, increase part_size", maxParts)
+		}
+
+		sum := md5.Sum(buf[:n])
+		sums = append(sums, sum[:]...)
+
+		tflog.Debug(ctx, fmt.Sprintf("upload part %d of object %s (%d bytes)", partNumber, aws.StringValue(input.Key), n))
+		out, err := client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     input.Bucket,
+			Key:        input.Key,
+			UploadId:   uploadID,
+			PartNumber: partNumber,
+			Body:       bytes.NewReader(buf[:n]),
+			ContentMD5: aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+
+			// every part of an object encrypted with a customer key is sent with the key
+			SSECustomerAlgorithm: input.SSECustomerAlgorithm,
+			SSECustomerKey:       input.SSECustomerKey,
+			SSECustomerKeyMD5:    input.SSECustomerKeyMD5,
+		})
+		if err != nil {
+			return uploadResult{}, fmt.Errorf("could not upload part %d: %w", partNumber, err)
+		}
+		parts = append(parts, s3types.CompletedPart{ETag: out.ETag, PartNumber: partNumber})
+
+		var readErr error
+		n, readErr = io.ReadFull(body, buf)
+		if readErr != nil && !errors.Is(readErr, io.ErrUnexpectedEOF) && !errors.Is(readErr, io.EOF) {
+			return uploadResult{}, readErr
+		}
+	}
+
+	out, err := client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          input.Bucket,
+		Key:             input.Key,
+		UploadId:        uploadID,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return uploadResult{}, fmt.Errorf("could not complete multipart upload: %w", err)
+	}
+
+	// the etag of a multipart object is the md5 of the md5 checksums of its parts
+	sum := md5.Sum(sums)
+	result := uploadResult{ETag: strings.Trim(aws.StringValue(out.ETag), `"`), VersionID: out.VersionId}
+	return result, verifyETag(result.ETag, fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(parts)))
+}
+
+// verifyETag compares the ETag returned by the gateway with the expected ETag.
+// Gateways might not return an ETag, e.g. for encrypted objects, which passes.
+func verifyETag(etag, expected string) error {
+	if etag == "" || etag == expected {
+		return nil
+	}
+	return fmt.Errorf("checksum mismatch: the gateway stored the object with ETag %s, expected %s", etag, expected)
+}
@@ -0,0 +1,481 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/smithy-go"
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	// defaultMaxPolicySize is the maximum size of a policy document in bytes accepted by default.
+	defaultMaxPolicySize = 20 * 1024
+
+	// maxReportedStatements limits the statements listed in policy limit errors.
+	maxReportedStatements = 5
+)
+
+// policyEquivalent reports whether two JSON policy documents are semantically equal,
+// ignoring formatting, key order and whether single values are wrapped in a list.
+func policyEquivalent(a, b string) bool {
+	var va, vb interface{}
+	if err := json.Unmarshal([]byte(a), &va); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalizePolicyValue(va), normalizePolicyValue(vb))
+}
+
+// normalizePolicyValue unwraps single element lists, e.g. `"Action": ["s3:GetObject"]`
+// is equivalent to `"Action": "s3:GetObject"`.
+func normalizePolicyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizePolicyValue(e)
+		}
+		return v
+	case []interface{}:
+		if len(v) == 1 {
+			return normalizePolicyValue(v[0])
+		}
+		for i, e := range v {
+			v[i] = normalizePolicyValue(e)
+		}
+		return v
+	}
+	return v
+}
+
+// normalizePolicyDocument decodes policy documents returned URL-encoded by the IAM api.
+func normalizePolicyDocument(policy string) string {
+	if json.Valid([]byte(policy)) {
+		return policy
+	}
+	if decoded, err := url.QueryUnescape(policy); err == nil && json.Valid([]byte(decoded)) {
+		return decoded
+	}
+	return policy
+}
+
+// isNoSuchBucketPolicy reports whether err signals that a bucket has no policy attached.
+func isNoSuchBucketPolicy(err error) bool {
+	var ae smithy.APIError
+	return errors.As(err, &ae) && ae.ErrorCode() == "NoSuchBucketPolicy"
+}
+
+// getBucketPolicy returns the policy of a bucket, an empty string if none is set.
+func getBucketPolicy(ctx context.Context, client *s3.Client, bucket string) (string, error) {
+	s3res, err := client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		if isNoSuchBucketPolicy(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return aws.StringValue(s3res.Policy), nil
+}
+
+const (
+	defaultPolicyPropagationTimeout = 60
+	policyPropagationReads          = 3
+	policyPropagationInterval       = time.Second
+)
+
+// waitForBucketPolicy reads the policy of a bucket until it is equivalent to the expected
+// policy in policyPropagationReads consecutive reads, an empty policy expects no policy.
+// Several reads are required as gateways behind a load balancer can serve stale policies
+// for a short time after a change, one of them answering with the new policy does not
+// mean all of them do.
+func waitForBucketPolicy(ctx context.Context, client *s3.Client, bucket, policy string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	matches := 0
+	for {
+		current, err := getBucketPolicy(ctx, client, bucket)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if err == nil && (current == policy || policyEquivalent(current, policy)) {
+			matches++
+		} else {
+			matches = 0
+		}
+		if matches >= policyPropagationReads {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("policy of bucket %s did not propagate within %s", bucket, timeout)
+		case <-time.After(policyPropagationInterval):
+		}
+	}
+}
+
+// policyDocument is a bucket policy with its statements decoded, all other
+// elements are kept as they are.
+type policyDocument struct {
+	elements   map[string]json.RawMessage
+	Statements []map[string]interface{}
+}
+
+// parsePolicyDocument decodes a bucket policy, an empty policy results in an empty document.
+func parsePolicyDocument(policy string) (*policyDocument, error) {
+	doc := &policyDocument{elements: map[string]json.RawMessage{}}
+	if policy == "" {
+		return doc, nil
+	}
+	if err := json.Unmarshal([]byte(policy), &doc.elements); err != nil {
+		return nil, fmt.Errorf("could not decode policy: %w", err)
+	}
+
+	statements, err := parseStatements(doc.elements["Statement"])
+	if err != nil {
+		return nil, err
+	}
+	doc.Statements = statements
+	delete(doc.elements, "Statement")
+
+	return doc, nil
+}
+
+// parseStatements decodes a single statement or a list of statements.
+func parseStatements(raw json.RawMessage) ([]map[string]interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var statements []map[string]interface{}
+	if err := json.Unmarshal(raw, &statements); err == nil {
+		return statements, nil
+	}
+
+	var statement map[string]interface{}
+	if err := json.Unmarshal(raw, &statement); err != nil {
+		return nil, fmt.Errorf("could not decode policy statements: %w", err)
+	}
+	return []map[string]interface{}{statement}, nil
+}
+
+// String encodes the document, an empty string is returned if it has no statements.
+func (doc *policyDocument) String() (string, error) {
+	if len(doc.Statements) == 0 {
+		return "", nil
+	}
+
+	elements := make(map[string]interface{}, len(doc.elements)+1)
+	for k, v := range doc.elements {
+		elements[k] = v
+	}
+	if _, ok := elements["Version"]; !ok {
+		elements["Version"] = "2012-10-17"
+	}
+	elements["Statement"] = doc.Statements
+
+	policy, err := json.Marshal(elements)
+	return string(policy), err
+}
+
+// statement returns the statement with the given Sid.
+func (doc *policyDocument) statement(sid string) (map[string]interface{}, bool) {
+	for _, st := range doc.Statements {
+		if statementSid(st) == sid {
+			return st, true
+		}
+	}
+	return nil, false
+}
+
+// removeStatements removes all statements with one of the given Sids.
+func (doc *policyDocument) removeStatements(sids []string) {
+	statements := doc.Statements[:0]
+	for _, st := range doc.Statements {
+		if !containsString(sids, statementSid(st)) {
+			statements = append(statements, st)
+		}
+	}
+	doc.Statements = statements
+}
+
+func statementSid(statement map[string]interface{}) string {
+	sid, _ := statement["Sid"].(string)
+	return sid
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+const policyModifyRetries = 5
+
+// errPolicyConflict is returned if the policy of a bucket kept changing during a modification.
+var errPolicyConflict = errors.New("bucket policy was modified concurrently")
+
+// modifyBucketPolicy applies fn to the current policy of a bucket and writes it back.
+// S3 has no conditional writes for bucket policies, so the policy is read again right
+// before writing and the modification is retried if it changed in the meantime. The
+// policy is deleted if no statements remain.
+func modifyBucketPolicy(ctx context.Context, client *RgwClient, bucket string, fn func(doc *policyDocument) error) error {
+	defer client.lockBucketPolicy(bucket)()
+
+	for attempt := 1; attempt <= policyModifyRetries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		current, err := getBucketPolicy(ctx, client.S3, bucket)
+		if err != nil {
+			return err
+		}
+
+		doc, err := parsePolicyDocument(current)
+		if err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+		policy, err := doc.String()
+		if err != nil {
+			return err
+		}
+		if policy == current || policyEquivalent(policy, current) {
+			return nil
+		}
+
+		// guard against modifications by others since the policy was read
+		check, err := getBucketPolicy(ctx, client.S3, bucket)
+		if err != nil {
+			return err
+		}
+		if check != current {
+			continue
+		}
+
+		if policy == "" {
+			_, err = client.S3.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
+				Bucket: aws.String(bucket),
+			})
+		} else {
+			_, err = client.S3.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+				Bucket: aws.String(bucket),
+				Policy: aws.String(policy),
+			})
+		}
+		return err
+	}
+
+	return fmt.Errorf("%w: gave up after %d attempts", errPolicyConflict, policyModifyRetries)
+}
+
+// attachPolicyStatements replaces the statements with the Sids in owned by the given statements
+// in the policy of a bucket. Existing statements with one of the new Sids not in owned belong
+// to someone else and result in an error.
+func attachPolicyStatements(ctx context.Context, client *RgwClient, bucket string, owned []string, statements []map[string]interface{}) error {
+	return retryNoSuchBucket(ctx, func() error {
+		return modifyBucketPolicy(ctx, client, bucket, func(doc *policyDocument) error {
+			for _, st := range statements {
+				sid := statementSid(st)
+				if _, ok := doc.statement(sid); ok && !containsString(owned, sid) {
+					return fmt.Errorf("statement with Sid '%s' already exists in the policy of bucket %s", sid, bucket)
+				}
+			}
+			doc.removeStatements(owned)
+			doc.Statements = append(doc.Statements, statements...)
+			return nil
+		})
+	})
+}
+
+// statementLabel describes a statement by its Sid or its position for error messages.
+func statementLabel(i int, statement map[string]interface{}) string {
+	if sid := statementSid(statement); sid != "" {
+		return fmt.Sprintf("Sid '%s'", sid)
+	}
+	return fmt.Sprintf("statement %d", i)
+}
+
+// validatePolicyLimits checks the size and the number of statements of a policy document
+// against the limits of the provider configuration. Errors point to the largest statements
+// or the statements exceeding the limit, so generated policies can be split up easily.
+func validatePolicyLimits(client *RgwClient, attr path.Path, policy string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if client == nil {
+		return diags
+	}
+
+	// the size is checked even if the document is invalid, the gateway reports the syntax error
+	doc, err := parsePolicyDocument(policy)
+	if err != nil {
+		doc = &policyDocument{}
+	}
+
+	if client.MaxPolicySize > 0 && len(policy) > client.MaxPolicySize {
+		type statementSize struct {
+			label string
+			size  int
+		}
+		sizes := make([]statementSize, 0, len(doc.Statements))
+		for i, st := range doc.Statements {
+			encoded, _ := json.Marshal(st)
+			sizes = append(sizes, statementSize{statementLabel(i, st), len(encoded)})
+		}
+		sort.SliceStable(sizes, func(i, j int) bool { return sizes[i].size > sizes[j].size })
+
+		detail := fmt.Sprintf("The policy document is %d bytes, the gateway accepts at most %d bytes (max_policy_size of the provider).", len(policy), client.MaxPolicySize)
+		if len(sizes) > 0 {
+			var largest []string
+			for _, s := range sizes[:min(len(sizes), maxReportedStatements)] {
+				largest = append(largest, fmt.Sprintf("%s (%d bytes)", s.label, s.size))
+			}
+			detail += " Largest statements: " + strings.Join(largest, ", ") + "."
+		}
+		diags.AddAttributeError(attr, "policy too large", detail)
+	}
+
+	if client.MaxPolicyStatements > 0 && len(doc.Statements) > client.MaxPolicyStatements {
+		var exceeding []string
+		for i, st := range doc.Statements[client.MaxPolicyStatements:] {
+			if i == maxReportedStatements {
+				exceeding = append(exceeding, fmt.Sprintf("and %d more", len(doc.Statements)-client.MaxPolicyStatements-i))
+				break
+			}
+			exceeding = append(exceeding, statementLabel(client.MaxPolicyStatements+i, st))
+		}
+		diags.AddAttributeError(attr, "too many policy statements", fmt.Sprintf("The policy has %d statements, at most %d are allowed (max_policy_statements of the provider). Statements exceeding the limit: %s.", len(doc.Statements), client.MaxPolicyStatements, strings.Join(exceeding, ", ")))
+	}
+
+	return diags
+}
+
+// modifyPolicyLimitsPlan validates the planned policy document of the attribute against the
+// limits of the provider, see validatePolicyLimits.
+func modifyPolicyLimitsPlan(ctx context.Context, client *RgwClient, attribute string, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var policy types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root(attribute), &policy)...)
+	if resp.Diagnostics.HasError() || policy.IsNull() || policy.IsUnknown() {
+		return
+	}
+	resp.Diagnostics.Append(validatePolicyLimits(client, path.Root(attribute), policy.ValueString())...)
+}
+
+// policyPrincipals returns the AWS principals of the Principal and NotPrincipal elements of a statement.
+func policyPrincipals(statement map[string]interface{}) []string {
+	var principals []string
+	for _, element := range []string{"Principal", "NotPrincipal"} {
+		p, ok := statement[element].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch v := p["AWS"].(type) {
+		case string:
+			principals = append(principals, v)
+		case []interface{}:
+			for _, e := range v {
+				if s, ok := e.(string); ok {
+					principals = append(principals, s)
+				}
+			}
+		}
+	}
+	return principals
+}
+
+// principalExists looks up the user or role of a principal ARN. Principals which can not be
+// checked, e.g. wildcards, account principals or roles of other tenants, are reported as existing.
+func principalExists(ctx context.Context, client *RgwClient, principal string) (bool, error) {
+	// arn:aws:iam::<tenant>:<user|role>/<name>
+	parts := strings.SplitN(principal, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "iam" {
+		return true, nil
+	}
+	tenant := parts[4]
+	kind, name, _ := strings.Cut(parts[5], "/")
+	if name == "" || strings.Contains(name, "*") {
+		return true, nil
+	}
+
+	switch kind {
+	case "user":
+		_, err := client.Admin.GetUser(ctx, admin.User{ID: tenantedUID(tenant, name)})
+		if errors.Is(err, admin.ErrNoSuchUser) {
+			return false, nil
+		}
+		return err == nil, err
+	case "role":
+		// the iam api only sees the roles of the tenant of the provider user
+		if tenant != "" || client.IAM == nil {
+			return true, nil
+		}
+		// role names are the last element of the path
+		_, err := client.IAM.GetRoleWithContext(ctx, &iam.GetRoleInput{
+			RoleName: aws.String(name[strings.LastIndex(name, "/")+1:]),
+		})
+		if isNoSuchEntity(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return true, nil
+}
+
+// validatePolicyPrincipals warns about principals of the statements which do not refer to an
+// existing user or role. Such principals are accepted by the gateway but grant nothing, which
+// usually hints at a typo.
+func validatePolicyPrincipals(ctx context.Context, client *RgwClient, attr path.Path, statements []map[string]interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if client == nil {
+		return diags
+	}
+
+	checked := map[string]bool{}
+	for i, st := range statements {
+		for _, principal := range policyPrincipals(st) {
+			if _, ok := checked[principal]; ok {
+				continue
+			}
+			exists, err := principalExists(ctx, client, principal)
+			checked[principal] = exists
+			if err != nil {
+				diags.AddAttributeWarning(attr, "could not check policy principal", fmt.Sprintf("could not look up principal %s of %s: %s", principal, statementLabel(i, st), err.Error()))
+				checked[principal] = true
+				continue
+			}
+			if !exists {
+				diags.AddAttributeWarning(attr, "unknown policy principal", fmt.Sprintf("principal %s of %s does not refer to an existing user or role, the statement grants nothing to it", principal, statementLabel(i, st)))
+			}
+		}
+	}
+	return diags
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &PresignedURLDataSource{}
+
+func NewPresignedURLDataSource() datasource.DataSource {
+	return &PresignedURLDataSource{}
+}
+
+type PresignedURLDataSource struct {
+	client *RgwClient
+}
+
+type PresignedURLDataSourceModel struct {
+	Bucket    types.String `tfsdk:"bucket"`
+	Key       types.String `tfsdk:"key"`
+	Method    types.String `tfsdk:"method"`
+	ExpiresIn types.Int64  `tfsdk:"expires_in"`
+	URL       types.String `tfsdk:"url"`
+}
+
+func (d *PresignedURLDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_presigned_url"
+}
+
+func (d *PresignedURLDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Presigned URL for an object, signed with the credentials of the provider. A new URL is generated on every refresh. The URL is stored in plain text in the state, being sensitive only hides it in the plan output, so anyone with access to the state can use it until it expires. Keep `expires_in` short.",
+
+		Attributes: map[string]schema.Attribute{
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+			},
+			"key": schema.StringAttribute{
+				MarkdownDescription: "Object key",
+				Required:            true,
+			},
+			"method": schema.StringAttribute{
+				MarkdownDescription: "HTTP method the URL is valid for - can be either `GET` or `PUT`. Defaults to `GET`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf("GET", "PUT"),
+				},
+			},
+			"expires_in": schema.Int64Attribute{
+				MarkdownDescription: "Validity of the URL in seconds. Defaults to `3600`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, 7*24*3600),
+				},
+			},
+			"url": schema.StringAttribute{
+				MarkdownDescription: "The presigned URL, stored in the state",
+				Computed:            true,
+				Sensitive:           true,
+			},
+		},
+	}
+}
+
+func (d *PresignedURLDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the data source can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_presigned_url")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.client = client
+}
+
+func (d *PresignedURLDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data PresignedURLDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	expires := time.Hour
+	if !data.ExpiresIn.IsNull() {
+		expires = time.Duration(data.ExpiresIn.ValueInt64()) * time.Second
+	}
+
+	presignClient := s3.NewPresignClient(d.client.S3, s3.WithPresignExpires(expires))
+
+	var url string
+	if data.Method.ValueString() == "PUT" {
+		presigned, err := presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(data.Bucket.ValueString()),
+			Key:    aws.String(data.Key.ValueString()),
+		})
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(d.client, "could not presign url", err)...)
+			return
+		}
+		url = presigned.URL
+	} else {
+		presigned, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(data.Bucket.ValueString()),
+			Key:    aws.String(data.Key.ValueString()),
+		})
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(d.client, "could not presign url", err)...)
+			return
+		}
+		url = presigned.URL
+	}
+
+	data.URL = types.StringValue(url)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &PrincipalArnFunction{}
+
+func NewPrincipalArnFunction() function.Function {
+	return &PrincipalArnFunction{}
+}
+
+type PrincipalArnFunction struct{}
+
+func (f *PrincipalArnFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "principal_arn"
+}
+
+func (f *PrincipalArnFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Build the principal ARN of a RGW user",
+		MarkdownDescription: "Expands a user ID, optionally qualified with a tenant as `tenant$uid`, into the principal ARN expected by RGW in bucket policies, e.g. `arn:aws:iam::tenant:user/uid`.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "uid",
+				MarkdownDescription: "The user ID, `uid` or `tenant$uid`",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *PrincipalArnFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var uid string
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &uid))
+	if resp.Error != nil {
+		return
+	}
+
+	if uid == "" {
+		resp.Error = function.NewArgumentFuncError(0, "uid must not be empty")
+		return
+	}
+
+	tenant, user, found := strings.Cut(uid, "$")
+	if !found {
+		tenant, user = "", uid
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, principalARN(tenant, user)))
+}
+
+// principalARN returns the principal ARN of a user to be used in policies.
+func principalARN(tenant, user string) string {
+	return fmt.Sprintf("arn:aws:iam::%s:user/%s", tenant, user)
+}
@@ -2,21 +2,34 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure RgwProvider satisfies various provider interfaces.
 var _ provider.Provider = &RgwProvider{}
+var _ provider.ProviderWithFunctions = &RgwProvider{}
 
 // RgwProvider defines the provider implementation.
 type RgwProvider struct {
@@ -28,14 +41,69 @@ type RgwProvider struct {
 
 // RgwProviderModel describes the provider data model.
 type RgwProviderModel struct {
-	Endpoint  types.String `tfsdk:"endpoint"`
-	AccessKey types.String `tfsdk:"access_key"`
-	SecretKey types.String `tfsdk:"secret_key"`
+	Endpoint    types.String `tfsdk:"endpoint"`
+	AccessKey   types.String `tfsdk:"access_key"`
+	SecretKey   types.String `tfsdk:"secret_key"`
+	Token       types.String `tfsdk:"session_token"`
+	DefaultTags types.Map    `tfsdk:"default_tags"`
+	MaxFailures types.Int64  `tfsdk:"max_consecutive_failures"`
+	SkipStats   types.Bool   `tfsdk:"skip_refresh_stats"`
+	S3Disabled  types.Bool   `tfsdk:"s3_disabled"`
+	PathStyle   types.Bool   `tfsdk:"use_path_style"`
+	ReadOnly    types.Bool   `tfsdk:"read_only"`
+
+	AuditLogPath   types.String `tfsdk:"audit_log_path"`
+	AuditLogBucket types.String `tfsdk:"audit_log_bucket"`
+
+	MaxPolicySize       types.Int64 `tfsdk:"max_policy_size"`
+	MaxPolicyStatements types.Int64 `tfsdk:"max_policy_statements"`
+
+	DefaultUserQuota   *DefaultQuotaModel `tfsdk:"default_user_quota"`
+	DefaultBucketQuota *DefaultQuotaModel `tfsdk:"default_bucket_quota"`
+}
+
+// DefaultQuotaModel describes a quota applied to all users created by the provider.
+type DefaultQuotaModel struct {
+	MaxSizeBytes types.Int64 `tfsdk:"max_size_bytes"`
+	MaxObjects   types.Int64 `tfsdk:"max_objects"`
 }
 
 type RgwClient struct {
-	Admin *admin.API
-	S3    *s3.Client
+	Admin       *admin.API
+	S3          *s3.Client
+	STS         *sts.STS
+	IAM         *iam.IAM
+	SNS         *sns.SNS
+	DefaultTags map[string]string
+
+	// S3Options are the options of the S3 client, to derive clients signing with other keys.
+	S3Options s3.Options
+
+	// CephRelease is the major version of Ceph the gateway runs, 0 if unknown.
+	CephRelease int
+
+	// SkipRefreshStats skips fetching bucket info and statistics when refreshing buckets.
+	SkipRefreshStats bool
+
+	// DefaultUserQuota and DefaultBucketQuota are set on new users, nil if not configured.
+	DefaultUserQuota   *admin.QuotaSpec
+	DefaultBucketQuota *admin.QuotaSpec
+
+	// MaxPolicySize and MaxPolicyStatements limit policy documents at plan time, 0 if not limited.
+	MaxPolicySize       int
+	MaxPolicyStatements int
+
+	// ReadOnly rejects all changes of resources, see requireWritable.
+	ReadOnly bool
+
+	// Errors coalesces errors with the same root cause, see errorDiagnostics.
+	Errors *errorTracker
+
+	// UserLocks serializes modifications of the same user, see lockUser.
+	UserLocks *keyedMutex
+
+	// BucketPolicyLocks serializes modifications of the policy of the same bucket, see lockBucketPolicy.
+	BucketPolicyLocks *keyedMutex
 }
 
 func (p *RgwProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -59,6 +127,91 @@ func (p *RgwProvider) Schema(ctx context.Context, req provider.SchemaRequest, re
 				Optional:            true,
 				Sensitive:           true,
 			},
+			"session_token": schema.StringAttribute{
+				MarkdownDescription: "Session token of temporary credentials issued by STS, e.g. with `AssumeRole`. `access_key` and `secret_key` are the temporary keys then. Should be set via env 'TF_PROVIDER_RGW_SESSION_TOKEN'",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"default_tags": schema.MapAttribute{
+				MarkdownDescription: "Tags applied to all resources supporting tags. Resource tags with the same key take precedence.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"max_consecutive_failures": schema.Int64Attribute{
+				MarkdownDescription: "Number of consecutive failed requests after which all further requests to the endpoint fail fast for 30 seconds instead of running into timeouts. Set to `0` to disable. Defaults to `5`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
+			"max_policy_size": schema.Int64Attribute{
+				MarkdownDescription: "Maximum size of policy documents in bytes, checked at plan time. Set to the policy size limit of the gateway, `0` disables the check. Defaults to `20480`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
+			"max_policy_statements": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of statements of policy documents, checked at plan time. Defaults to `0`, which does not limit the statements.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
+			"s3_disabled": schema.BoolAttribute{
+				MarkdownDescription: "Do not create an S3 client, e.g. for workspaces which can only reach the admin api. Resources and data sources requiring the S3 API fail with an error. Defaults to `false`.",
+				Optional:            true,
+			},
+			"read_only": schema.BoolAttribute{
+				MarkdownDescription: "Fail on any change of a resource, while refreshing resources and reading data sources still works. Plans show the changes as usual, applying them fails before any request is sent. Use it for audit-only plans, e.g. from less-trusted CI runners, together with credentials limited to read caps, as the flag is only enforced by the provider. Defaults to `false`.",
+				Optional:            true,
+			},
+			"audit_log_path": schema.StringAttribute{
+				MarkdownDescription: "Append a JSON line for every mutating request to this local file, with the time, the access key of the caller, the operation, the target and the status of the request. Secrets in request parameters are redacted. Reads are not recorded.",
+				Optional:            true,
+			},
+			"audit_log_bucket": schema.StringAttribute{
+				MarkdownDescription: "Store a JSON object for every mutating request in this bucket, with the same content as the lines of `audit_log_path`. The bucket must exist and be writable with the provider credentials. Requires the S3 API.",
+				Optional:            true,
+			},
+			"use_path_style": schema.BoolAttribute{
+				MarkdownDescription: "Address buckets path-style (`https://endpoint/bucket`) in S3 requests and presigned URLs. Set to `false` for gateways requiring virtual-host-style addressing (`https://bucket.endpoint`), which needs `rgw_dns_name` and a wildcard DNS record. Bucket names which are not valid host names, e.g. of tenanted buckets, are always addressed path-style. Defaults to `true`.",
+				Optional:            true,
+			},
+			"skip_refresh_stats": schema.BoolAttribute{
+				MarkdownDescription: "Skip fetching bucket statistics on refresh. Owner, statistics and placement of existing buckets and the usage of `rgw_quota` keep the values of the last apply, which speeds up the refresh of large workspaces considerably. Defaults to `false`.",
+				Optional:            true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"default_user_quota": schema.SingleNestedBlock{
+				MarkdownDescription: "User quota set on every user created by `rgw_user`. A `rgw_quota` or `rgw_quota_set` of the user overrides it.",
+				Attributes:          defaultQuotaAttributes(),
+			},
+			"default_bucket_quota": schema.SingleNestedBlock{
+				MarkdownDescription: "Bucket quota set on every user created by `rgw_user`, applying to each of its buckets. A `rgw_quota` or `rgw_quota_set` of the user overrides it.",
+				Attributes:          defaultQuotaAttributes(),
+			},
+		},
+	}
+}
+
+// defaultQuotaAttributes returns the attributes of the default quota blocks.
+func defaultQuotaAttributes() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"max_size_bytes": schema.Int64Attribute{
+			MarkdownDescription: "The maximum size in bytes, `-1` if unlimited. Defaults to `-1`.",
+			Optional:            true,
+			Validators: []validator.Int64{
+				int64validator.AtLeast(-1),
+			},
+		},
+		"max_objects": schema.Int64Attribute{
+			MarkdownDescription: "The maximum number of objects, `-1` if unlimited. Defaults to `-1`.",
+			Optional:            true,
+			Validators: []validator.Int64{
+				int64validator.AtLeast(-1),
+			},
 		},
 	}
 }
@@ -83,36 +236,160 @@ func (p *RgwProvider) Configure(ctx context.Context, req provider.ConfigureReque
 		data.SecretKey = types.StringValue(os.Getenv("TF_PROVIDER_RGW_SECRET_KEY"))
 	}
 
+	if data.Token.IsNull() {
+		data.Token = types.StringValue(os.Getenv("TF_PROVIDER_RGW_SESSION_TOKEN"))
+	}
+
+	maxFailures := defaultMaxConsecutiveFailures
+	if !data.MaxFailures.IsNull() {
+		maxFailures = int(data.MaxFailures.ValueInt64())
+	}
+
+	maxPolicySize := defaultMaxPolicySize
+	if !data.MaxPolicySize.IsNull() {
+		maxPolicySize = int(data.MaxPolicySize.ValueInt64())
+	}
+
+	// Shared HTTP client of the admin and s3 clients, failing fast on a flapping gateway
+	var transport http.RoundTripper = http.DefaultTransport
+	if data.Token.ValueString() != "" {
+		transport = newSessionTokenTransport(transport, data.AccessKey.ValueString(), data.Token.ValueString())
+	}
+	breaker := newCircuitBreakerTransport(transport, data.Endpoint.ValueString(), maxFailures)
+	httpClient := &http.Client{
+		Transport: breaker,
+	}
+
 	// Create Ceph RGW Admin Client
 	tflog.Debug(ctx, "Configuring Ceph RGW admin client")
-	admin, err := admin.New(data.Endpoint.ValueString(), data.AccessKey.ValueString(), data.SecretKey.ValueString(), nil)
+	admin, err := admin.New(data.Endpoint.ValueString(), data.AccessKey.ValueString(), data.SecretKey.ValueString(), httpClient)
 	if err != nil {
 		resp.Diagnostics.AddError("could not create rgw admin client", err.Error())
 		return
 	}
 
-	// Create s3 client
-	tflog.Debug(ctx, "Configuring S3 client from AWS SDK")
-	s3client := s3.New(s3.Options{
-		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
-			return aws.Credentials{
-				AccessKeyID:     data.AccessKey.ValueString(),
-				SecretAccessKey: data.SecretKey.ValueString(),
-			}, nil
-		}),
-		EndpointResolver: s3.EndpointResolverFromURL(data.Endpoint.ValueString()),
-		UsePathStyle:     true,
+	// Create s3 client unless disabled
+	var s3client *s3.Client
+	var s3Options s3.Options
+	if !data.S3Disabled.ValueBool() {
+		tflog.Debug(ctx, "Configuring S3 client from AWS SDK")
+		s3Options = s3.Options{
+			Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+				return aws.Credentials{
+					AccessKeyID:     data.AccessKey.ValueString(),
+					SecretAccessKey: data.SecretKey.ValueString(),
+					SessionToken:    data.Token.ValueString(),
+				}, nil
+			}),
+			EndpointResolver: s3.EndpointResolverFromURL(data.Endpoint.ValueString()),
+			UsePathStyle:     data.PathStyle.IsNull() || data.PathStyle.ValueBool(),
+			HTTPClient:       httpClient,
+		}
+		s3client = s3.New(s3Options)
+	}
+
+	// Record mutating requests of all clients, the audit bucket is written without the
+	// audit transport so the records are not audited themselves
+	if data.AuditLogPath.ValueString() != "" || data.AuditLogBucket.ValueString() != "" {
+		var auditS3 *s3.Client
+		if data.AuditLogBucket.ValueString() != "" {
+			if data.S3Disabled.ValueBool() {
+				resp.Diagnostics.AddError("s3 api disabled", "audit_log_bucket requires the S3 API, which is disabled by s3_disabled in the provider configuration")
+				return
+			}
+			auditOptions := s3Options
+			auditOptions.HTTPClient = &http.Client{Transport: breaker}
+			auditS3 = s3.New(auditOptions)
+		}
+		httpClient.Transport = newAuditTransport(breaker, data.AuditLogPath.ValueString(), auditS3, data.AuditLogBucket.ValueString())
+	}
+
+	// Create sts, iam and sns clients from the v1 SDK, which already bundles these APIs
+	tflog.Debug(ctx, "Configuring STS, IAM and SNS clients from AWS SDK")
+	sess, err := session.NewSession(&awsv1.Config{
+		Endpoint:    awsv1.String(data.Endpoint.ValueString()),
+		Region:      awsv1.String("default"),
+		Credentials: credentials.NewStaticCredentials(data.AccessKey.ValueString(), data.SecretKey.ValueString(), data.Token.ValueString()),
+		HTTPClient:  httpClient,
 	})
+	if err != nil {
+		resp.Diagnostics.AddError("could not create aws sdk session", err.Error())
+		return
+	}
+
+	defaultTags, diags := tagsFromMap(ctx, data.DefaultTags)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the version is detected from an anonymous s3 request
+	cephRelease := 0
+	if !data.S3Disabled.ValueBool() {
+		cephRelease = detectCephRelease(ctx, httpClient, data.Endpoint.ValueString())
+	}
 
 	client := &RgwClient{
-		Admin: admin,
-		S3:    s3client,
+		Admin:       admin,
+		S3:          s3client,
+		STS:         sts.New(sess),
+		IAM:         iam.New(sess),
+		SNS:         sns.New(sess),
+		DefaultTags: defaultTags,
+		CephRelease: cephRelease,
+		S3Options:   s3Options,
+
+		SkipRefreshStats: data.SkipStats.ValueBool(),
+		ReadOnly:         data.ReadOnly.ValueBool(),
+
+		DefaultUserQuota:   defaultQuotaSpec(data.DefaultUserQuota, "user"),
+		DefaultBucketQuota: defaultQuotaSpec(data.DefaultBucketQuota, "bucket"),
+
+		MaxPolicySize:       maxPolicySize,
+		MaxPolicyStatements: int(data.MaxPolicyStatements.ValueInt64()),
+
+		Errors:            newErrorTracker(),
+		UserLocks:         newKeyedMutex(),
+		BucketPolicyLocks: newKeyedMutex(),
 	}
 
 	resp.DataSourceData = client
 	resp.ResourceData = client
 }
 
+// lockUser serializes modifications of the user with the (tenanted) uid and returns
+// the function to unlock it. The gateway rewrites the whole user info on each change,
+// so concurrent changes of keys, caps, quotas or subusers of one user can overwrite
+// each other. Changes of different users are not serialized.
+func (c *RgwClient) lockUser(uid string) func() {
+	return c.UserLocks.lock(uid)
+}
+
+// lockBucketPolicy serializes modifications of the policy of a bucket within the provider
+// and returns the function to unlock it.
+func (c *RgwClient) lockBucketPolicy(bucket string) func() {
+	return c.BucketPolicyLocks.lock(bucket)
+}
+
+// requireS3 fails if the s3 client is disabled in the provider configuration.
+func requireS3(client *RgwClient, typeName string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if client.S3 == nil {
+		diags.AddError("s3 api disabled", fmt.Sprintf("%s requires the S3 API, which is disabled by s3_disabled in the provider configuration", typeName))
+	}
+	return diags
+}
+
+// requireWritable fails if the provider is configured as read-only. It is called before
+// any request of Create, Update and Delete.
+func requireWritable(client *RgwClient) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if client.ReadOnly {
+		diags.AddError("provider is read-only", "resources can not be created, modified or deleted as read_only is set in the provider configuration")
+	}
+	return diags
+}
+
 func (p *RgwProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewBucketResource,
@@ -121,11 +398,47 @@ func (p *RgwProvider) Resources(ctx context.Context) []func() resource.Resource
 		NewBucketLinkResource,
 		NewQuotaResource,
 		NewBucketQuotaResource,
+		NewUsageTrimResource,
+		NewBucketObjectLockResource,
+		NewQuotaSetResource,
+		NewBucketPolicyAttachmentResource,
+		NewTenantBucketShareResource,
+		NewRoleResource,
+		NewLogTrimResource,
+		NewBucketObjectResource,
+		NewTopicResource,
+		NewBucketNotificationResource,
+		NewBucketObjectsSyncResource,
+		NewBucketLifecycleResource,
+		NewSubuserResource,
+		NewUserRateLimitResource,
+		NewBucketRateLimitResource,
+		NewBucketCorsResource,
 	}
 }
 
 func (p *RgwProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{}
+	return []func() datasource.DataSource{
+		NewPresignedURLDataSource,
+		NewMetadataDataSource,
+		NewUserBulkDataSource,
+		NewSTSSessionDataSource,
+		NewBucketObjectsDataSource,
+		NewBucketInventoryDataSource,
+		NewBucketDataSource,
+		NewZoneDataSource,
+		NewSyncStatusDataSource,
+		NewBucketNotificationDataSource,
+		NewUsageDataSource,
+		NewBucketObjectLocksDataSource,
+	}
+}
+
+func (p *RgwProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		NewPrincipalArnFunction,
+		NewValidateBucketNameFunction,
+	}
 }
 
 func New(version string) func() provider.Provider {
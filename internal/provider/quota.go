@@ -0,0 +1,312 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// quotaLimitAttributes returns the schema attributes shared by all quota resources.
+func quotaLimitAttributes() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"enabled": schema.BoolAttribute{
+			MarkdownDescription: "Enable or disable the quota",
+			Optional:            true,
+			Computed:            true,
+			Default:             booldefault.StaticBool(true),
+			PlanModifiers: []planmodifier.Bool{
+				boolplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"check_on_raw": schema.BoolAttribute{
+			MarkdownDescription: "Check the size limit against the actual sizes of the objects instead of their sizes rounded up to 4 KiB",
+			Optional:            true,
+			Computed:            true,
+			Default:             booldefault.StaticBool(false),
+			PlanModifiers: []planmodifier.Bool{
+				boolplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"max_size": schema.Int64Attribute{
+			MarkdownDescription: "The maximum size of the quota in bytes, `-1` if unlimited",
+			Computed:            true,
+		},
+		"max_size_kb": schema.Int64Attribute{
+			MarkdownDescription: "The maximum size of the quota in kilobytes, `0` if unlimited. Conflicts with `max_size_bytes`.",
+			Optional:            true,
+			Computed:            true,
+			Validators: []validator.Int64{
+				int64validator.AtLeast(0),
+				int64validator.ConflictsWith(path.MatchRoot("max_size_bytes")),
+			},
+		},
+		"max_size_bytes": schema.Int64Attribute{
+			MarkdownDescription: "The maximum size of the quota in bytes, `-1` if unlimited. Other negative values are rejected. Conflicts with `max_size_kb`.",
+			Optional:            true,
+			Computed:            true,
+			Validators: []validator.Int64{
+				int64validator.AtLeast(-1),
+			},
+		},
+		"preserve_on_destroy": schema.BoolAttribute{
+			MarkdownDescription: "Only disable the quota when the resource is destroyed and keep the configured limits on the gateway, so re-enabling the quota manually restores them. By default the limits are reset to unlimited.",
+			Optional:            true,
+			Computed:            true,
+			Default:             booldefault.StaticBool(false),
+		},
+		"max_objects": schema.Int64Attribute{
+			MarkdownDescription: "The maximum number of objects in the quota, `-1` if unlimited. Other negative values are rejected. Defaults to `-1`.",
+			Optional:            true,
+			Computed:            true,
+			Default:             int64default.StaticInt64(-1),
+			Validators: []validator.Int64{
+				int64validator.AtLeast(-1),
+			},
+			PlanModifiers: []planmodifier.Int64{
+				int64planmodifier.UseStateForUnknown(),
+			},
+		},
+	}
+}
+
+// quotaUsageAttributes returns the read-only usage attributes of quota resources.
+// The usage is refreshed on read only, so updating the limits does not cause a diff.
+func quotaUsageAttributes(attributes map[string]schema.Attribute, scope string) {
+	attributes["used_size_bytes"] = schema.Int64Attribute{
+		MarkdownDescription: fmt.Sprintf("The current size of %s in bytes as counted by the quota, i.e. the raw size if `check_on_raw` is set and the size rounded to 4 KiB blocks otherwise", scope),
+		Computed:            true,
+		PlanModifiers: []planmodifier.Int64{
+			int64planmodifier.UseStateForUnknown(),
+		},
+	}
+	attributes["used_objects"] = schema.Int64Attribute{
+		MarkdownDescription: fmt.Sprintf("The current number of objects of %s", scope),
+		Computed:            true,
+		PlanModifiers: []planmodifier.Int64{
+			int64planmodifier.UseStateForUnknown(),
+		},
+	}
+}
+
+// quotaUsageFromStats returns the usage attributes for the statistics returned by the api.
+func quotaUsageFromStats(stats rgwUsageStats, checkOnRaw bool, usedSize, usedObjects *types.Int64) {
+	size := stats.SizeActual
+	if checkOnRaw {
+		size = stats.Size
+	}
+	*usedSize = types.Int64Value(int64(size))
+	*usedObjects = types.Int64Value(int64(stats.NumObjects))
+}
+
+// setQuotaLimits sets the quota limits of the api request from the resource attributes.
+// maxSize is the normalized size in bytes, see modifyQuotaSizePlan.
+func setQuotaLimits(quota *admin.QuotaSpec, enabled, checkOnRaw types.Bool, maxSize, maxObjects types.Int64) {
+	enabledValue := enabled.ValueBool()
+	quota.Enabled = &enabledValue
+	quota.CheckOnRaw = checkOnRaw.ValueBool()
+
+	maxSizeValue := int64(-1)
+	if !maxSize.IsNull() && !maxSize.IsUnknown() {
+		maxSizeValue = maxSize.ValueInt64()
+	}
+	quota.MaxSize = &maxSizeValue
+
+	if !maxObjects.IsNull() {
+		maxObjectsValue := maxObjects.ValueInt64()
+		quota.MaxObjects = &maxObjectsValue
+	}
+}
+
+// defaultQuotaSpec returns the quota of a default quota block of the provider, nil if the block is not set.
+func defaultQuotaSpec(model *DefaultQuotaModel, quotaType string) *admin.QuotaSpec {
+	if model == nil {
+		return nil
+	}
+
+	quota := admin.QuotaSpec{QuotaType: quotaType}
+	setQuotaLimits(&quota, types.BoolValue(true), types.BoolValue(false), model.MaxSizeBytes, model.MaxObjects)
+	if quota.MaxObjects == nil {
+		maxObjects := int64(-1)
+		quota.MaxObjects = &maxObjects
+	}
+	return &quota
+}
+
+// setDefaultQuotas sets the default quotas of the provider on a new user.
+func setDefaultQuotas(ctx context.Context, client *RgwClient, uid string) error {
+	for _, defaultQuota := range []*admin.QuotaSpec{client.DefaultUserQuota, client.DefaultBucketQuota} {
+		if defaultQuota == nil {
+			continue
+		}
+		quota := *defaultQuota
+		quota.UID = uid
+		set := client.Admin.SetUserQuota
+		if quota.QuotaType == "bucket" {
+			set = client.Admin.SetBucketQuota
+		}
+		if err := set(ctx, quota); err != nil {
+			return fmt.Errorf("could not set default %s quota: %w", quota.QuotaType, err)
+		}
+	}
+	return nil
+}
+
+// disableQuota disables the quota of the api request and resets its limits unless preserveLimits is set.
+func disableQuota(quota *admin.QuotaSpec, preserveLimits bool) {
+	f := false
+	quota.Enabled = &f
+	if preserveLimits {
+		return
+	}
+	maxSize := int64(-1)
+	quota.MaxSize = &maxSize
+	quota.MaxSizeKb = nil
+	maxObjects := int64(-1)
+	quota.MaxObjects = &maxObjects
+}
+
+// modifyQuotaSizePlan normalizes max_size, max_size_kb and max_size_bytes from whichever is configured.
+// A max_size_kb of 0 is treated as unlimited for compatibility.
+func modifyQuotaSizePlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var maxSizeKB, maxSizeBytes types.Int64
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("max_size_kb"), &maxSizeKB)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("max_size_bytes"), &maxSizeBytes)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if maxSizeKB.IsUnknown() || maxSizeBytes.IsUnknown() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("max_size"), types.Int64Unknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("max_size_kb"), types.Int64Unknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("max_size_bytes"), types.Int64Unknown())...)
+		return
+	}
+
+	size := int64(-1)
+	if !maxSizeBytes.IsNull() {
+		size = maxSizeBytes.ValueInt64()
+	} else if !maxSizeKB.IsNull() && maxSizeKB.ValueInt64() > 0 {
+		size = maxSizeKB.ValueInt64() * 1024
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("max_size"), types.Int64Value(size))...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("max_size_kb"), types.Int64Value(quotaSizeKB(size)))...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("max_size_bytes"), types.Int64Value(size))...)
+}
+
+// quotaSizeKB rounds a size in bytes up to kilobytes the same way RGW does, -1 results in 0.
+func quotaSizeKB(size int64) int64 {
+	return (size + 1023) / 1024
+}
+
+// unlimitedQuota normalizes a quota limit returned by the api. RGW treats any negative
+// limit as unlimited, e.g. set with radosgw-admin, which is always reported as -1.
+func unlimitedQuota(limit int64) int64 {
+	if limit < 0 {
+		return -1
+	}
+	return limit
+}
+
+// quotaLimitsFromSpec returns the resource attributes for the quota limits returned by the api.
+func quotaLimitsFromSpec(quota admin.QuotaSpec, enabled, checkOnRaw *types.Bool, maxSize, maxSizeKB, maxSizeBytes, maxObjects *types.Int64) {
+	if quota.Enabled != nil {
+		*enabled = types.BoolValue(*quota.Enabled)
+	}
+	*checkOnRaw = types.BoolValue(quota.CheckOnRaw)
+	if quota.MaxSize != nil {
+		size := unlimitedQuota(*quota.MaxSize)
+		*maxSize = types.Int64Value(size)
+		*maxSizeBytes = types.Int64Value(size)
+		*maxSizeKB = types.Int64Value(quotaSizeKB(size))
+	}
+	if quota.MaxObjects != nil {
+		*maxObjects = types.Int64Value(unlimitedQuota(*quota.MaxObjects))
+	}
+}
+
+// quotaLimitsEqual reports whether two quotas have the same limits.
+func quotaLimitsEqual(a, b admin.QuotaSpec) bool {
+	return valueOrDefault(a.Enabled, false) == valueOrDefault(b.Enabled, false) &&
+		a.CheckOnRaw == b.CheckOnRaw &&
+		unlimitedQuota(valueOrDefault(a.MaxSize, -1)) == unlimitedQuota(valueOrDefault(b.MaxSize, -1)) &&
+		unlimitedQuota(valueOrDefault(a.MaxObjects, -1)) == unlimitedQuota(valueOrDefault(b.MaxObjects, -1))
+}
+
+func valueOrDefault[T any](v *T, def T) T {
+	if v == nil {
+		return def
+	}
+	return *v
+}
+
+// errNoSuchAccount is returned by the admin api for unknown account ids.
+var errNoSuchAccount = errors.New("NoSuchAccount")
+
+// rgwAccount describes the parts of an RGW account used by the provider.
+type rgwAccount struct {
+	ID          string          `json:"id"`
+	Tenant      string          `json:"tenant"`
+	Name        string          `json:"name"`
+	Quota       admin.QuotaSpec `json:"quota"`
+	BucketQuota admin.QuotaSpec `json:"bucket_quota"`
+}
+
+// getAccountQuota gets the quota of an RGW account.
+func getAccountQuota(ctx context.Context, api *admin.API, accountID string) (admin.QuotaSpec, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/account", url.Values{
+		"id": {accountID},
+	})
+	if err != nil {
+		return admin.QuotaSpec{}, err
+	}
+
+	var account rgwAccount
+	if err := json.Unmarshal(body, &account); err != nil {
+		return admin.QuotaSpec{}, fmt.Errorf("could not decode account: %w", err)
+	}
+
+	return account.Quota, nil
+}
+
+// setAccountQuota sets the quota of an RGW account, quota.UID holds the account id.
+func setAccountQuota(ctx context.Context, api *admin.API, quota admin.QuotaSpec) error {
+	args := url.Values{
+		"id":         {quota.UID},
+		"quota-type": {"account"},
+	}
+	if quota.Enabled != nil {
+		args.Set("enabled", strconv.FormatBool(*quota.Enabled))
+	}
+	if quota.MaxSize != nil {
+		args.Set("max-size", strconv.FormatInt(*quota.MaxSize, 10))
+	}
+	if quota.MaxObjects != nil {
+		args.Set("max-objects", strconv.FormatInt(*quota.MaxObjects, 10))
+	}
+
+	_, err := adminCall(ctx, api, http.MethodPut, "/account?quota", args)
+	return err
+}
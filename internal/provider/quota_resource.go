@@ -7,12 +7,9 @@ import (
 
 	"github.com/ceph/go-ceph/rgw/admin"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
@@ -21,6 +18,7 @@ import (
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.ResourceWithConfigure = &QuotaResource{}
+var _ resource.ResourceWithModifyPlan = &QuotaResource{}
 
 func NewQuotaResource() resource.Resource {
 	return &QuotaResource{}
@@ -31,13 +29,18 @@ type QuotaResource struct {
 }
 
 type QuotaResourceModel struct {
-	UID        types.String `tfsdk:"uid"`
-	Type       types.String `tfsdk:"type"`
-	Enabled    types.Bool   `tfsdk:"enabled"`
-	CheckOnRaw types.Bool   `tfsdk:"check_on_raw"`
-	MaxSize    types.Int64  `tfsdk:"max_size"`
-	MaxSizeKB  types.Int64  `tfsdk:"max_size_kb"`
-	MaxObjects types.Int64  `tfsdk:"max_objects"`
+	UID          types.String `tfsdk:"uid"`
+	Tenant       types.String `tfsdk:"tenant"`
+	Type         types.String `tfsdk:"type"`
+	Enabled      types.Bool   `tfsdk:"enabled"`
+	CheckOnRaw   types.Bool   `tfsdk:"check_on_raw"`
+	MaxSize      types.Int64  `tfsdk:"max_size"`
+	MaxSizeKB    types.Int64  `tfsdk:"max_size_kb"`
+	MaxSizeBytes types.Int64  `tfsdk:"max_size_bytes"`
+	MaxObjects   types.Int64  `tfsdk:"max_objects"`
+	Preserve     types.Bool   `tfsdk:"preserve_on_destroy"`
+	UsedSize     types.Int64  `tfsdk:"used_size_bytes"`
+	UsedObjects  types.Int64  `tfsdk:"used_objects"`
 }
 
 func (r *QuotaResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -45,69 +48,52 @@ func (r *QuotaResource) Metadata(ctx context.Context, req resource.MetadataReque
 }
 
 func (r *QuotaResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
-	resp.Schema = schema.Schema{
-		MarkdownDescription: "This resource can be used to set the quota for a rgw user. Refer to the Ceph RGW Admin Ops API documentation for values documentation. Upon deletion, quota is disabled.",
-
-		Attributes: map[string]schema.Attribute{
-			"uid": schema.StringAttribute{
-				MarkdownDescription: "The UID of the user to set the quota for.",
-				Required:            true,
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
-			},
-			"type": schema.StringAttribute{
-				MarkdownDescription: "Quota type - can be either `user` or `bucket` (for buckets owned by user).",
-				Required:            true,
-				Validators: []validator.String{
-					stringvalidator.OneOf([]string{"user", "bucket"}...),
-				},
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
-			},
-			"enabled": schema.BoolAttribute{
-				MarkdownDescription: "Enable or disable the quota",
-				Optional:            true,
-				Computed:            true,
-				Default:             booldefault.StaticBool(true),
-				PlanModifiers: []planmodifier.Bool{
-					boolplanmodifier.UseStateForUnknown(),
-				},
-			},
-			"check_on_raw": schema.BoolAttribute{
-				MarkdownDescription: "???",
-				Optional:            true,
-				Computed:            true,
-				Default:             booldefault.StaticBool(false),
-				PlanModifiers: []planmodifier.Bool{
-					boolplanmodifier.UseStateForUnknown(),
-				},
-			},
-			"max_size": schema.Int64Attribute{
-				MarkdownDescription: "The maximum size of the quota",
-				Computed:            true,
-			},
-			"max_size_kb": schema.Int64Attribute{
-				MarkdownDescription: "The maximum size of the quota in kilobytes",
-				Optional:            true,
-				Computed:            true,
-				Default:             int64default.StaticInt64(0),
-				PlanModifiers: []planmodifier.Int64{
-					int64planmodifier.UseStateForUnknown(),
-				},
-			},
-			"max_objects": schema.Int64Attribute{
-				MarkdownDescription: "The maximum number of objects in the quota",
-				Optional:            true,
-				Computed:            true,
-				Default:             int64default.StaticInt64(-1),
-				PlanModifiers: []planmodifier.Int64{
-					int64planmodifier.UseStateForUnknown(),
-				},
-			},
+	attributes := quotaLimitAttributes()
+	attributes["uid"] = schema.StringAttribute{
+		MarkdownDescription: "The UID of the user to set the quota for.",
+		Required:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
 		},
 	}
+	attributes["type"] = schema.StringAttribute{
+		MarkdownDescription: "Quota type - can be either `user`, `bucket` (for buckets owned by user) or `account` (for RGW accounts, `uid` is the account ID).",
+		Required:            true,
+		Validators: []validator.String{
+			stringvalidator.OneOf([]string{"user", "bucket", "account"}...),
+		},
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+	attributes["tenant"] = schema.StringAttribute{
+		MarkdownDescription: "The tenant of the user. If set, `uid` is qualified as `tenant$uid` for all api calls.",
+		Optional:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+
+	quotaUsageAttributes(attributes, "the user (`user`), its fullest bucket (`bucket`) or the account (`account`, not reported by the gateway and always null)")
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "This resource can be used to set the quota for a rgw user. Refer to the Ceph RGW Admin Ops API documentation for values documentation. Upon deletion, quota is disabled and its limits are reset unless `preserve_on_destroy` is set.",
+		Attributes:          attributes,
+	}
+}
+
+func (r *QuotaResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	modifyQuotaSizePlan(ctx, req, resp)
+	if resp.Diagnostics.HasError() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	// accounts are supported since squid
+	var quotaType types.String
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("type"), &quotaType)...)
+	if quotaType.ValueString() == "account" {
+		resp.Diagnostics.Append(requireCephRelease(r.client, 19, "account quotas")...)
+	}
 }
 
 func (r *QuotaResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
@@ -131,32 +117,95 @@ func (r *QuotaResource) Configure(ctx context.Context, req resource.ConfigureReq
 }
 
 func rgwQuotaFromSchemaQuota(data *QuotaResourceModel) admin.QuotaSpec {
-	enabled := data.Enabled.ValueBool()
 	quota := admin.QuotaSpec{
-		UID:        data.UID.ValueString(),
-		QuotaType:  data.Type.ValueString(),
-		Enabled:    &enabled,
-		CheckOnRaw: data.CheckOnRaw.ValueBool(),
+		UID:       tenantedUID(data.Tenant.ValueString(), data.UID.ValueString()),
+		QuotaType: data.Type.ValueString(),
+	}
+	// account ids are global
+	if quota.QuotaType == "account" {
+		quota.UID = data.UID.ValueString()
 	}
+	setQuotaLimits(&quota, data.Enabled, data.CheckOnRaw, data.MaxSize, data.MaxObjects)
 
-	// treat 0 as max_size quote disabled
-	if !data.MaxSizeKB.IsNull() && data.MaxSizeKB.ValueInt64() != 0 {
-		maxSizeKb := int(data.MaxSizeKB.ValueInt64())
-		quota.MaxSizeKb = &maxSizeKb
-	} else {
-		maxSize := int64(-1)
-		quota.MaxSize = &maxSize
+	return quota
+}
+
+// setQuota sets the quota depending on the quota type.
+func (r *QuotaResource) setQuota(ctx context.Context, quota admin.QuotaSpec) error {
+	switch quota.QuotaType {
+	case "user":
+		defer r.client.lockUser(quota.UID)()
+		return r.client.Admin.SetUserQuota(ctx, quota)
+	case "account":
+		return setAccountQuota(ctx, r.client.Admin, quota)
+	default:
+		defer r.client.lockUser(quota.UID)()
+		return r.client.Admin.SetBucketQuota(ctx, quota)
+	}
+}
+
+// getUsage gets the usage the quota is checked against depending on the quota type.
+// Bucket quotas apply to each bucket individually, so the usage of the fullest bucket
+// is returned. The usage of accounts is not reported, nil is returned for them.
+func (r *QuotaResource) getUsage(ctx context.Context, quota admin.QuotaSpec) (*rgwUsageStats, error) {
+	switch quota.QuotaType {
+	case "user":
+		stats, err := getUserStats(ctx, r.client.Admin, quota.UID)
+		if err != nil {
+			return nil, err
+		}
+		return &stats, nil
+	case "account":
+		return nil, nil
+	default:
+		buckets, err := getUserBucketStats(ctx, r.client.Admin, quota.UID)
+		if err != nil {
+			return nil, err
+		}
+		var stats rgwUsageStats
+		for _, bucket := range buckets {
+			bucketStats := bucketUsageStats(bucket.Bucket)
+			stats.Size = max(stats.Size, bucketStats.Size)
+			stats.SizeActual = max(stats.SizeActual, bucketStats.SizeActual)
+			stats.NumObjects = max(stats.NumObjects, bucketStats.NumObjects)
+		}
+		return &stats, nil
 	}
+}
 
-	if !data.MaxObjects.IsNull() {
-		maxObjects := data.MaxObjects.ValueInt64()
-		quota.MaxObjects = &maxObjects
+// setUsage sets the usage attributes of the model.
+func (r *QuotaResource) setUsage(ctx context.Context, data *QuotaResourceModel, quota admin.QuotaSpec) error {
+	stats, err := r.getUsage(ctx, quota)
+	if err != nil {
+		return err
+	}
+	if stats == nil {
+		data.UsedSize = types.Int64Null()
+		data.UsedObjects = types.Int64Null()
+		return nil
 	}
+	quotaUsageFromStats(*stats, data.CheckOnRaw.ValueBool(), &data.UsedSize, &data.UsedObjects)
+	return nil
+}
 
-	return quota
+// getQuota gets the quota depending on the quota type.
+func (r *QuotaResource) getQuota(ctx context.Context, quota admin.QuotaSpec) (admin.QuotaSpec, error) {
+	switch quota.QuotaType {
+	case "user":
+		return r.client.Admin.GetUserQuota(ctx, quota)
+	case "account":
+		return getAccountQuota(ctx, r.client.Admin, quota.UID)
+	default:
+		return r.client.Admin.GetBucketQuota(ctx, quota)
+	}
 }
 
 func (r *QuotaResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *QuotaResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -166,21 +215,15 @@ func (r *QuotaResource) Create(ctx context.Context, req resource.CreateRequest,
 
 	quota := rgwQuotaFromSchemaQuota(data)
 
-	var err error
-	if data.Type.ValueString() == "user" {
-		err = r.client.Admin.SetUserQuota(ctx, quota)
-	} else {
-		err = r.client.Admin.SetBucketQuota(ctx, quota)
-	}
+	err := r.setQuota(ctx, quota)
 	if err != nil {
-		resp.Diagnostics.AddError("could not create user quota", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create user quota", err)...)
 		return
 	}
 
-	if data.MaxSizeKB.ValueInt64() != 0 {
-		data.MaxSize = types.Int64Value(data.MaxSizeKB.ValueInt64() * 1024)
-	} else {
-		data.MaxSize = types.Int64Value(-1)
+	if err := r.setUsage(ctx, data, quota); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get quota usage", err)...)
+		return
 	}
 
 	// Save data into Terraform state
@@ -196,40 +239,58 @@ func (r *QuotaResource) Read(ctx context.Context, req resource.ReadRequest, resp
 	}
 
 	// prepare request attributes
-	reqQuotaSpec := admin.QuotaSpec{
-		UID: data.UID.ValueString(),
-	}
+	reqQuotaSpec := rgwQuotaFromSchemaQuota(data)
 
-	// get user quota
-	var err error
-	var quotaSpec admin.QuotaSpec
-	if data.Type.ValueString() == "user" {
-		quotaSpec, err = r.client.Admin.GetUserQuota(ctx, reqQuotaSpec)
-	} else {
-		quotaSpec, err = r.client.Admin.GetBucketQuota(ctx, reqQuotaSpec)
-	}
+	// get quota
+	quotaSpec, err := r.getQuota(ctx, reqQuotaSpec)
 	if err != nil {
-		if errors.Is(err, admin.ErrNoSuchUser) {
+		if errors.Is(err, admin.ErrNoSuchUser) || errors.Is(err, errNoSuchAccount) {
 			// Remove user from state
 			resp.State.RemoveResource(ctx)
 			return
 		}
-		resp.Diagnostics.AddError("could not get user quota", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get user quota", err)...)
 		return
 	}
 
-	if quotaSpec.Enabled != nil {
-		data.Enabled = types.BoolValue(*quotaSpec.Enabled)
-	}
-	data.CheckOnRaw = types.BoolValue(quotaSpec.CheckOnRaw)
-	if quotaSpec.MaxSize != nil {
-		data.MaxSize = types.Int64Value(*quotaSpec.MaxSize)
-	}
-	if quotaSpec.MaxSizeKb != nil {
-		data.MaxSizeKB = types.Int64Value(int64(*quotaSpec.MaxSizeKb))
+	// verify the gateway returned the quota of the requested scope, as some gateways
+	// ignore the quota type and always return the same quota
+	if reqQuotaSpec.QuotaType != "account" {
+		user, err := r.client.Admin.GetUser(ctx, admin.User{ID: reqQuotaSpec.UID})
+		if err != nil {
+			if errors.Is(err, admin.ErrNoSuchUser) {
+				resp.State.RemoveResource(ctx)
+				return
+			}
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get user", err)...)
+			return
+		}
+
+		scoped := user.UserQuota
+		if reqQuotaSpec.QuotaType == "bucket" {
+			scoped = user.BucketQuota
+		}
+		if !quotaLimitsEqual(quotaSpec, scoped) {
+			resp.Diagnostics.AddWarning(
+				"quota type mismatch",
+				fmt.Sprintf("the gateway returned a %s quota for user %s which differs from the %s quota reported in the user info. Using the quota of the user info, the gateway might not support the quota-type parameter.", reqQuotaSpec.QuotaType, reqQuotaSpec.UID, reqQuotaSpec.QuotaType),
+			)
+			quotaSpec = scoped
+		}
 	}
-	if quotaSpec.MaxObjects != nil {
-		data.MaxObjects = types.Int64Value(*quotaSpec.MaxObjects)
+
+	quotaLimitsFromSpec(quotaSpec, &data.Enabled, &data.CheckOnRaw, &data.MaxSize, &data.MaxSizeKB, &data.MaxSizeBytes, &data.MaxObjects)
+
+	// get the usage, unless skipped for quotas already known, e.g. not just imported
+	if !r.client.SkipRefreshStats || data.UsedObjects.IsNull() {
+		if err := r.setUsage(ctx, data, reqQuotaSpec); err != nil {
+			if errors.Is(err, admin.ErrNoSuchUser) {
+				resp.State.RemoveResource(ctx)
+				return
+			}
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get quota usage", err)...)
+			return
+		}
 	}
 
 	// Save updated data into Terraform state
@@ -237,6 +298,11 @@ func (r *QuotaResource) Read(ctx context.Context, req resource.ReadRequest, resp
 }
 
 func (r *QuotaResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *QuotaResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -246,27 +312,21 @@ func (r *QuotaResource) Update(ctx context.Context, req resource.UpdateRequest,
 
 	quota := rgwQuotaFromSchemaQuota(data)
 
-	var err error
-	if data.Type.ValueString() == "user" {
-		err = r.client.Admin.SetUserQuota(ctx, quota)
-	} else {
-		err = r.client.Admin.SetBucketQuota(ctx, quota)
-	}
+	err := r.setQuota(ctx, quota)
 	if err != nil {
-		resp.Diagnostics.AddError("could not modify user quota", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify user quota", err)...)
 		return
 	}
-	if data.MaxSizeKB.ValueInt64() != 0 {
-		data.MaxSize = types.Int64Value(data.MaxSizeKB.ValueInt64() * 1024)
-	} else {
-		data.MaxSize = types.Int64Value(-1)
-	}
-
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *QuotaResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform prior state data into the model
 	var data *QuotaResourceModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -275,27 +335,12 @@ func (r *QuotaResource) Delete(ctx context.Context, req resource.DeleteRequest,
 	}
 
 	quota := rgwQuotaFromSchemaQuota(data)
-	f := false
-	quota.Enabled = &f
-	maxSize := int64(-1)
-	quota.MaxSize = &maxSize
-	quota.MaxSizeKb = nil
-	maxObjects := int64(-1)
-	quota.MaxObjects = &maxObjects
-
-	var err error
-	if data.Type.ValueString() == "user" {
-		err = r.client.Admin.SetUserQuota(ctx, quota)
-	} else {
-		err = r.client.Admin.SetBucketQuota(ctx, quota)
-	}
-	if err != nil {
-		resp.Diagnostics.AddError("could not modify user quota", err.Error())
-		return
-	}
+	disableQuota(&quota, data.Preserve.ValueBool())
 
-	if err != nil && !errors.Is(err, admin.ErrNoSuchUser) {
-		resp.Diagnostics.AddError("could not delete user quota", err.Error())
+	// nothing to disable if the user or account is already gone
+	err := r.setQuota(ctx, quota)
+	if err != nil && !errors.Is(err, admin.ErrNoSuchUser) && !errors.Is(err, admin.ErrNoSuchBucket) && !errors.Is(err, errNoSuchAccount) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete user quota", err)...)
 		return
 	}
 }
@@ -0,0 +1,386 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &QuotaSetResource{}
+
+func NewQuotaSetResource() resource.Resource {
+	return &QuotaSetResource{}
+}
+
+type QuotaSetResource struct {
+	client *RgwClient
+}
+
+type QuotaSetResourceModel struct {
+	Id           types.String                     `tfsdk:"id"`
+	Type         types.String                     `tfsdk:"type"`
+	Tenant       types.String                     `tfsdk:"tenant"`
+	Enabled      types.Bool                       `tfsdk:"enabled"`
+	CheckOnRaw   types.Bool                       `tfsdk:"check_on_raw"`
+	MaxSizeBytes types.Int64                      `tfsdk:"max_size_bytes"`
+	MaxObjects   types.Int64                      `tfsdk:"max_objects"`
+	Concurrency  types.Int64                      `tfsdk:"concurrency"`
+	Preserve     types.Bool                       `tfsdk:"preserve_on_destroy"`
+	Users        map[string]QuotaSetOverrideModel `tfsdk:"users"`
+}
+
+// QuotaSetOverrideModel holds the per-user overrides of the quota template, null values use the template.
+type QuotaSetOverrideModel struct {
+	Enabled      types.Bool  `tfsdk:"enabled"`
+	MaxSizeBytes types.Int64 `tfsdk:"max_size_bytes"`
+	MaxObjects   types.Int64 `tfsdk:"max_objects"`
+}
+
+func (r *QuotaSetResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_quota_set"
+}
+
+func (r *QuotaSetResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	limits := quotaLimitAttributes()
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Applies the same quota template to many users at once, with optional per-user overrides. Quotas are applied concurrently and kept in a single resource, which keeps state size and refresh time low compared to one `rgw_quota` per user. Upon deletion, the quota of all users is disabled and its limits are reset unless `preserve_on_destroy` is set.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"type": schema.StringAttribute{
+				MarkdownDescription: "Quota type - can be either `user` or `bucket` (for buckets owned by user).",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf("user", "bucket"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"tenant": schema.StringAttribute{
+				MarkdownDescription: "The tenant of the users. If set, all uids are qualified as `tenant$uid` for all api calls.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"enabled": schema.BoolAttribute{
+				MarkdownDescription: limits["enabled"].GetMarkdownDescription(),
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"check_on_raw": schema.BoolAttribute{
+				MarkdownDescription: limits["check_on_raw"].GetMarkdownDescription(),
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"max_size_bytes": schema.Int64Attribute{
+				MarkdownDescription: "The maximum size of the quota in bytes, `-1` if unlimited",
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(-1),
+				Validators: []validator.Int64{
+					int64validator.AtLeast(-1),
+				},
+			},
+			"max_objects": schema.Int64Attribute{
+				MarkdownDescription: "The maximum number of objects in the quota, `-1` if unlimited",
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(-1),
+				Validators: []validator.Int64{
+					int64validator.AtLeast(-1),
+				},
+			},
+			"preserve_on_destroy": limits["preserve_on_destroy"],
+			"concurrency": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of concurrent api calls. Defaults to `10`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, 100),
+				},
+			},
+			"users": schema.MapNestedAttribute{
+				MarkdownDescription: "The users to apply the quota to, keyed by uid. Each entry can override the limits of the template.",
+				Required:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"enabled": schema.BoolAttribute{
+							MarkdownDescription: "Override `enabled` for this user",
+							Optional:            true,
+						},
+						"max_size_bytes": schema.Int64Attribute{
+							MarkdownDescription: "Override `max_size_bytes` for this user",
+							Optional:            true,
+							Validators: []validator.Int64{
+								int64validator.AtLeast(-1),
+							},
+						},
+						"max_objects": schema.Int64Attribute{
+							MarkdownDescription: "Override `max_objects` for this user",
+							Optional:            true,
+							Validators: []validator.Int64{
+								int64validator.AtLeast(-1),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *QuotaSetResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+// quotaSpec returns the effective quota of a user from the template and its overrides.
+func (data *QuotaSetResourceModel) quotaSpec(uid string) admin.QuotaSpec {
+	quota := admin.QuotaSpec{
+		UID:       tenantedUID(data.Tenant.ValueString(), uid),
+		QuotaType: data.Type.ValueString(),
+	}
+
+	enabled, maxSize, maxObjects := data.Enabled, data.MaxSizeBytes, data.MaxObjects
+	if override, ok := data.Users[uid]; ok {
+		if !override.Enabled.IsNull() {
+			enabled = override.Enabled
+		}
+		if !override.MaxSizeBytes.IsNull() {
+			maxSize = override.MaxSizeBytes
+		}
+		if !override.MaxObjects.IsNull() {
+			maxObjects = override.MaxObjects
+		}
+	}
+	setQuotaLimits(&quota, enabled, data.CheckOnRaw, maxSize, maxObjects)
+
+	return quota
+}
+
+func (data *QuotaSetResourceModel) concurrency() int {
+	if data.Concurrency.IsNull() {
+		return defaultConcurrency
+	}
+	return int(data.Concurrency.ValueInt64())
+}
+
+func (data *QuotaSetResourceModel) uids() []string {
+	uids := make([]string, 0, len(data.Users))
+	for uid := range data.Users {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+// setQuota sets the quota depending on the quota type.
+func (r *QuotaSetResource) setQuota(ctx context.Context, quota admin.QuotaSpec) error {
+	defer r.client.lockUser(quota.UID)()
+	if quota.QuotaType == "user" {
+		return r.client.Admin.SetUserQuota(ctx, quota)
+	}
+	return r.client.Admin.SetBucketQuota(ctx, quota)
+}
+
+// getQuota gets the quota depending on the quota type.
+func (r *QuotaSetResource) getQuota(ctx context.Context, quota admin.QuotaSpec) (admin.QuotaSpec, error) {
+	if quota.QuotaType == "user" {
+		return r.client.Admin.GetUserQuota(ctx, quota)
+	}
+	return r.client.Admin.GetBucketQuota(ctx, quota)
+}
+
+// applyQuotas sets the quota of the given users concurrently.
+func (r *QuotaSetResource) applyQuotas(ctx context.Context, data *QuotaSetResourceModel, uids []string) map[string]error {
+	tflog.Info(ctx, fmt.Sprintf("apply %s quota to %d users", data.Type.ValueString(), len(uids)))
+	return forEachConcurrent(ctx, uids, data.concurrency(), func(ctx context.Context, uid string) error {
+		return r.setQuota(ctx, data.quotaSpec(uid))
+	})
+}
+
+// disableQuotas disables the quota of the given users concurrently, ignoring users which do not exist anymore.
+// The limits are kept if preserve_on_destroy is set.
+func (r *QuotaSetResource) disableQuotas(ctx context.Context, data *QuotaSetResourceModel, uids []string) map[string]error {
+	tflog.Info(ctx, fmt.Sprintf("disable %s quota of %d users", data.Type.ValueString(), len(uids)))
+	return forEachConcurrent(ctx, uids, data.concurrency(), func(ctx context.Context, uid string) error {
+		quota := data.quotaSpec(uid)
+		disableQuota(&quota, data.Preserve.ValueBool())
+		if err := r.setQuota(ctx, quota); err != nil && !errors.Is(err, admin.ErrNoSuchUser) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *QuotaSetResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *QuotaSetResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue(tenantedUID(data.Tenant.ValueString(), data.Type.ValueString()))
+
+	if errs := r.applyQuotas(ctx, data, data.uids()); len(errs) > 0 {
+		resp.Diagnostics.AddError(fmt.Sprintf("could not set quota of %d users", len(errs)), joinKeyErrors(errs))
+	}
+
+	// Save data into Terraform state, the resource is tainted on errors
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *QuotaSetResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *QuotaSetResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var mu sync.Mutex
+	users := make(map[string]QuotaSetOverrideModel, len(data.Users))
+
+	errs := forEachConcurrent(ctx, data.uids(), data.concurrency(), func(ctx context.Context, uid string) error {
+		want := data.quotaSpec(uid)
+		got, err := r.getQuota(ctx, want)
+		if err != nil {
+			// users which do not exist anymore are removed from the set
+			if errors.Is(err, admin.ErrNoSuchUser) {
+				return nil
+			}
+			return err
+		}
+
+		override := data.Users[uid]
+		if !quotaLimitsEqual(want, got) {
+			// report the actual limits as override to show the drift
+			quotaLimitsFromSpec(got, &override.Enabled, new(types.Bool), new(types.Int64), new(types.Int64), &override.MaxSizeBytes, &override.MaxObjects)
+		}
+
+		mu.Lock()
+		users[uid] = override
+		mu.Unlock()
+		return nil
+	})
+	if len(errs) > 0 {
+		resp.Diagnostics.AddError(fmt.Sprintf("could not get quota of %d users", len(errs)), joinKeyErrors(errs))
+		return
+	}
+	data.Users = users
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *QuotaSetResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *QuotaSetResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var dataState *QuotaSetResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &dataState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// only apply quotas which changed
+	var changed []string
+	for _, uid := range data.uids() {
+		if _, ok := dataState.Users[uid]; ok && quotaLimitsEqual(data.quotaSpec(uid), dataState.quotaSpec(uid)) {
+			continue
+		}
+		changed = append(changed, uid)
+	}
+	if errs := r.applyQuotas(ctx, data, changed); len(errs) > 0 {
+		resp.Diagnostics.AddError(fmt.Sprintf("could not set quota of %d users", len(errs)), joinKeyErrors(errs))
+		return
+	}
+
+	// disable quotas of removed users
+	var removed []string
+	for _, uid := range dataState.uids() {
+		if _, ok := data.Users[uid]; !ok {
+			removed = append(removed, uid)
+		}
+	}
+	if errs := r.disableQuotas(ctx, dataState, removed); len(errs) > 0 {
+		resp.Diagnostics.AddError(fmt.Sprintf("could not disable quota of %d users", len(errs)), joinKeyErrors(errs))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *QuotaSetResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *QuotaSetResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if errs := r.disableQuotas(ctx, data, data.uids()); len(errs) > 0 {
+		resp.Diagnostics.AddError(fmt.Sprintf("could not disable quota of %d users", len(errs)), joinKeyErrors(errs))
+		return
+	}
+}
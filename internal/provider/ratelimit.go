@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// rgwRateLimit is the rate limit of a user or bucket as reported by the admin api.
+type rgwRateLimit struct {
+	MaxReadOps    int64 `json:"max_read_ops"`
+	MaxWriteOps   int64 `json:"max_write_ops"`
+	MaxReadBytes  int64 `json:"max_read_bytes"`
+	MaxWriteBytes int64 `json:"max_write_bytes"`
+	Enabled       bool  `json:"enabled"`
+}
+
+// rateLimitAttributes returns the schema attributes shared by all rate limit resources.
+// The limits are not UseStateForUnknown, so limits modified out-of-band with
+// `radosgw-admin ratelimit set` are refreshed on read and planned back to the configuration.
+func rateLimitAttributes() map[string]schema.Attribute {
+	limit := func(description string) schema.Int64Attribute {
+		return schema.Int64Attribute{
+			MarkdownDescription: description + " per minute and gateway instance, `0` if unlimited. Defaults to `0`.",
+			Optional:            true,
+			Computed:            true,
+			Default:             int64default.StaticInt64(0),
+			Validators: []validator.Int64{
+				int64validator.AtLeast(0),
+			},
+		}
+	}
+
+	return map[string]schema.Attribute{
+		"enabled": schema.BoolAttribute{
+			MarkdownDescription: "Enable or disable the rate limit. Defaults to `true`.",
+			Optional:            true,
+			Computed:            true,
+			Default:             booldefault.StaticBool(true),
+		},
+		"max_read_ops":    limit("The maximum number of read requests"),
+		"max_write_ops":   limit("The maximum number of write requests"),
+		"max_read_bytes":  limit("The maximum number of bytes read"),
+		"max_write_bytes": limit("The maximum number of bytes written"),
+	}
+}
+
+// rateLimitFromSchema builds the rate limit from the attributes of a resource.
+func rateLimitFromSchema(enabled types.Bool, maxReadOps, maxWriteOps, maxReadBytes, maxWriteBytes types.Int64) rgwRateLimit {
+	return rgwRateLimit{
+		MaxReadOps:    maxReadOps.ValueInt64(),
+		MaxWriteOps:   maxWriteOps.ValueInt64(),
+		MaxReadBytes:  maxReadBytes.ValueInt64(),
+		MaxWriteBytes: maxWriteBytes.ValueInt64(),
+		Enabled:       enabled.ValueBool(),
+	}
+}
+
+// rateLimitToSchema updates the attributes of a resource from the rate limit.
+func rateLimitToSchema(limit rgwRateLimit, enabled *types.Bool, maxReadOps, maxWriteOps, maxReadBytes, maxWriteBytes *types.Int64) {
+	*enabled = types.BoolValue(limit.Enabled)
+	*maxReadOps = types.Int64Value(limit.MaxReadOps)
+	*maxWriteOps = types.Int64Value(limit.MaxWriteOps)
+	*maxReadBytes = types.Int64Value(limit.MaxReadBytes)
+	*maxWriteBytes = types.Int64Value(limit.MaxWriteBytes)
+}
+
+// rateLimitArgs returns the admin api parameters selecting the rate limit of a user or bucket.
+func rateLimitArgs(scope, uid, bucket string) url.Values {
+	args := url.Values{
+		"ratelimit-scope": {scope},
+	}
+	if uid != "" {
+		args.Set("uid", uid)
+	}
+	if bucket != "" {
+		args.Set("bucket", bucket)
+	}
+	return args
+}
+
+// getRateLimit fetches the rate limit of a user (scope `user`) or bucket (scope `bucket`).
+func getRateLimit(ctx context.Context, client *RgwClient, scope, uid, bucket string) (rgwRateLimit, error) {
+	body, err := adminCall(ctx, client.Admin, http.MethodGet, "/ratelimit", rateLimitArgs(scope, uid, bucket))
+	if err != nil {
+		return rgwRateLimit{}, err
+	}
+
+	// the limit is wrapped as {"user_ratelimit": {...}} or {"bucket_ratelimit": {...}}
+	var resp map[string]rgwRateLimit
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return rgwRateLimit{}, fmt.Errorf("could not decode rate limit: %w", err)
+	}
+	limit, ok := resp[scope+"_ratelimit"]
+	if !ok {
+		return rgwRateLimit{}, fmt.Errorf("the gateway did not return a %s rate limit: %s", scope, string(body))
+	}
+	return limit, nil
+}
+
+// setRateLimit sets the rate limit of a user (scope `user`) or bucket (scope `bucket`).
+func setRateLimit(ctx context.Context, client *RgwClient, scope, uid, bucket string, limit rgwRateLimit) error {
+	args := rateLimitArgs(scope, uid, bucket)
+	args.Set("max-read-ops", strconv.FormatInt(limit.MaxReadOps, 10))
+	args.Set("max-write-ops", strconv.FormatInt(limit.MaxWriteOps, 10))
+	args.Set("max-read-bytes", strconv.FormatInt(limit.MaxReadBytes, 10))
+	args.Set("max-write-bytes", strconv.FormatInt(limit.MaxWriteBytes, 10))
+	args.Set("enabled", strconv.FormatBool(limit.Enabled))
+
+	_, err := adminCall(ctx, client.Admin, http.MethodPost, "/ratelimit", args)
+	return err
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	notFoundRetries    = 5
+	notFoundRetryDelay = time.Second
+)
+
+// isNoSuchBucket reports whether err signals that a bucket does not exist (yet).
+func isNoSuchBucket(err error) bool {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		switch ae.ErrorCode() {
+		case "NoSuchBucket", "404":
+			return true
+		}
+	}
+	return false
+}
+
+// retryNoSuchBucket calls fn until it no longer fails with NoSuchBucket, with a
+// bounded number of attempts and exponential backoff. This covers gateway
+// propagation lag for buckets created in the same apply.
+func retryNoSuchBucket(ctx context.Context, fn func() error) error {
+	delay := notFoundRetryDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !isNoSuchBucket(err) || attempt >= notFoundRetries {
+			return err
+		}
+
+		tflog.Debug(ctx, "bucket not found, retrying", map[string]interface{}{
+			"attempt": attempt,
+			"delay":   delay.String(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
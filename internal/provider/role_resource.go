@@ -0,0 +1,474 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &RoleResource{}
+var _ resource.ResourceWithModifyPlan = &RoleResource{}
+
+func NewRoleResource() resource.Resource {
+	return &RoleResource{}
+}
+
+type RoleResource struct {
+	client *RgwClient
+}
+
+type RoleResourceModel struct {
+	Id                 types.String `tfsdk:"id"`
+	Name               types.String `tfsdk:"name"`
+	Path               types.String `tfsdk:"path"`
+	Description        types.String `tfsdk:"description"`
+	AssumeRolePolicy   types.String `tfsdk:"assume_role_policy"`
+	MaxSessionDuration types.Int64  `tfsdk:"max_session_duration"`
+	Boundary           types.String `tfsdk:"permissions_boundary"`
+	Arn                types.String `tfsdk:"arn"`
+	RoleID             types.String `tfsdk:"role_id"`
+	CreateDate         types.String `tfsdk:"create_date"`
+	Tags               types.Map    `tfsdk:"tags"`
+	TagsAll            types.Map    `tfsdk:"tags_all"`
+}
+
+func (r *RoleResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_role"
+}
+
+func (r *RoleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Role in Ceph RGW which can be assumed via STS",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Role Name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"path": schema.StringAttribute{
+				MarkdownDescription: "Path of the role. Defaults to `/`. The API can not move a role, changing the path replaces the role and invalidates its sessions.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("/"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"description": schema.StringAttribute{
+				MarkdownDescription: "Description of the role. Updated in place. Requires Ceph >= squid.",
+				Optional:            true,
+			},
+			"assume_role_policy": schema.StringAttribute{
+				MarkdownDescription: "Trust policy (JSON) defining who can assume the role. Compared semantically, so formatting changes of the gateway do not cause a diff.",
+				Required:            true,
+			},
+			"max_session_duration": schema.Int64Attribute{
+				MarkdownDescription: "Maximum session duration in seconds. Defaults to `3600`. Updated in place, existing sessions keep their duration.",
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(3600),
+				Validators: []validator.Int64{
+					int64validator.Between(3600, 43200),
+				},
+			},
+			"permissions_boundary": schema.StringAttribute{
+				MarkdownDescription: "ARN of the managed policy used as permissions boundary of the role, limiting the permissions of sessions assuming it regardless of the attached policies, e.g. to constrain roles created by app teams. Set and removed in place. Not implemented by Ceph up to tentacle, which is rejected at plan time if the release of the gateway is known. Otherwise the role is deleted again if the gateway rejects the boundary or does not report it back on creation.",
+				Optional:            true,
+			},
+			"arn": schema.StringAttribute{
+				MarkdownDescription: "ARN of the role",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"role_id": schema.StringAttribute{
+				MarkdownDescription: "Unique ID of the role",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"create_date": schema.StringAttribute{
+				MarkdownDescription: "Creation time of the role",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"tags": schema.MapAttribute{
+				MarkdownDescription: "Tags of the role, e.g. to be evaluated as `aws:PrincipalTag` in policies of sessions assuming the role. Merged over the `default_tags` of the provider.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"tags_all": schema.MapAttribute{
+				MarkdownDescription: "All tags of the role, including the `default_tags` of the provider",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *RoleResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *RoleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	var defaultTags map[string]string
+	if r.client != nil {
+		defaultTags = r.client.DefaultTags
+	}
+	modifyTagsAllPlan(ctx, defaultTags, req, resp)
+	modifyPolicyLimitsPlan(ctx, r.client, "assume_role_policy", req, resp)
+	if resp.Diagnostics.HasError() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	// role tags are supported since pacific
+	var tagsAll types.Map
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("tags_all"), &tagsAll)...)
+	if len(tagsAll.Elements()) > 0 {
+		resp.Diagnostics.Append(requireCephRelease(r.client, 16, "tags on rgw_role")...)
+	}
+
+	// role descriptions are supported since squid
+	var description types.String
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("description"), &description)...)
+	if !description.IsNull() {
+		resp.Diagnostics.Append(requireCephRelease(r.client, 19, "description on rgw_role")...)
+	}
+
+	// permissions boundaries are not implemented by the IAM API of any known release, fail
+	// before the role is created without its boundary
+	var boundary types.String
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("permissions_boundary"), &boundary)...)
+	if !boundary.IsNull() && r.client != nil && r.client.CephRelease != 0 && r.client.CephRelease <= cephReleases["tentacle"] {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("permissions_boundary"),
+			"unsupported ceph version",
+			fmt.Sprintf("permissions_boundary on rgw_role is not implemented by Ceph up to %s, the gateway runs %s", cephReleaseName(cephReleases["tentacle"]), cephReleaseName(r.client.CephRelease)),
+		)
+	}
+}
+
+// isNoSuchEntity reports whether err signals that an IAM entity does not exist.
+func isNoSuchEntity(err error) bool {
+	var ae awserr.Error
+	return errors.As(err, &ae) && ae.Code() == iam.ErrCodeNoSuchEntityException
+}
+
+// setRole updates the computed attributes and the trust policy from the api role.
+func (data *RoleResourceModel) setRole(role *iam.Role) {
+	data.Id = types.StringValue(aws.StringValue(role.RoleName))
+	data.Name = types.StringValue(aws.StringValue(role.RoleName))
+	data.Path = types.StringValue(aws.StringValue(role.Path))
+	data.Arn = types.StringValue(aws.StringValue(role.Arn))
+	data.RoleID = types.StringValue(aws.StringValue(role.RoleId))
+	data.CreateDate = types.StringValue(aws.TimeValue(role.CreateDate).Format(time.RFC3339))
+	if role.MaxSessionDuration != nil {
+		data.MaxSessionDuration = types.Int64Value(*role.MaxSessionDuration)
+	}
+	if aws.StringValue(role.Description) != "" || !data.Description.IsNull() {
+		data.Description = types.StringValue(aws.StringValue(role.Description))
+	}
+	data.Boundary = types.StringNull()
+	if role.PermissionsBoundary != nil && aws.StringValue(role.PermissionsBoundary.PermissionsBoundaryArn) != "" {
+		data.Boundary = types.StringValue(aws.StringValue(role.PermissionsBoundary.PermissionsBoundaryArn))
+	}
+
+	// keep the configured formatting if the policy is semantically equal
+	policy := normalizePolicyDocument(aws.StringValue(role.AssumeRolePolicyDocument))
+	if !policyEquivalent(policy, data.AssumeRolePolicy.ValueString()) {
+		data.AssumeRolePolicy = types.StringValue(policy)
+	}
+}
+
+// setRolePermissionsBoundary sets or, if boundary is empty, removes the permissions boundary
+// of the role. Gateways which accept but ignore the request are detected by reading the
+// role back, so a role is never left without its configured boundary unnoticed.
+func setRolePermissionsBoundary(ctx context.Context, client *iam.IAM, name, boundary string) error {
+	var err error
+	if boundary == "" {
+		_, err = client.DeleteRolePermissionsBoundaryWithContext(ctx, &iam.DeleteRolePermissionsBoundaryInput{
+			RoleName: aws.String(name),
+		})
+	} else {
+		tflog.Info(ctx, fmt.Sprintf("set permissions boundary %s on role %s", boundary, name))
+		_, err = client.PutRolePermissionsBoundaryWithContext(ctx, &iam.PutRolePermissionsBoundaryInput{
+			RoleName:            aws.String(name),
+			PermissionsBoundary: aws.String(boundary),
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	out, err := client.GetRoleWithContext(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return err
+	}
+	var got string
+	if out.Role.PermissionsBoundary != nil {
+		got = aws.StringValue(out.Role.PermissionsBoundary.PermissionsBoundaryArn)
+	}
+	if got != boundary {
+		return fmt.Errorf("the gateway reports permissions boundary '%s' instead of '%s' for role %s, it does not support permissions boundaries", got, boundary, name)
+	}
+	return nil
+}
+
+func (r *RoleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *RoleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tags, diags := tagsFromMap(ctx, data.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("create role %s", data.Name.ValueString()))
+
+	out, err := r.client.IAM.CreateRoleWithContext(ctx, &iam.CreateRoleInput{
+		RoleName:                 aws.String(data.Name.ValueString()),
+		Path:                     aws.String(data.Path.ValueString()),
+		Description:              data.Description.ValueStringPointer(),
+		AssumeRolePolicyDocument: aws.String(data.AssumeRolePolicy.ValueString()),
+		MaxSessionDuration:       aws.Int64(data.MaxSessionDuration.ValueInt64()),
+	})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create role", err)...)
+		return
+	}
+	boundary := data.Boundary
+	data.setRole(out.Role)
+
+	// delete the new role again if it can not be set up below, it must not be left behind
+	// without its permissions boundary or untracked by the state
+	defer func() {
+		if resp.Diagnostics.HasError() {
+			r.deleteFailedRole(ctx, data.Name.ValueString(), &resp.Diagnostics)
+		}
+	}()
+
+	// set the boundary separately, so gateways ignoring it on creation are detected
+	if !boundary.IsNull() {
+		if err := setRolePermissionsBoundary(ctx, r.client.IAM, data.Name.ValueString(), boundary.ValueString()); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not set role permissions boundary", err)...)
+			return
+		}
+		data.Boundary = boundary
+	}
+
+	// set tags separately, as not every gateway version accepts them on creation
+	if len(tags) > 0 {
+		if err := updateRoleTags(ctx, r.client.IAM, data.Name.ValueString(), nil, tags); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not set role tags", err)...)
+			return
+		}
+	}
+	data.TagsAll = tagsToMap(tags)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// deleteFailedRole deletes a role which was created but could not be set up.
+func (r *RoleResource) deleteFailedRole(ctx context.Context, name string, diags *diag.Diagnostics) {
+	tflog.Info(ctx, fmt.Sprintf("delete role %s which could not be set up", name))
+	_, err := r.client.IAM.DeleteRoleWithContext(context.WithoutCancel(ctx), &iam.DeleteRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil && !isNoSuchEntity(err) {
+		diags.AddWarning("could not delete role", fmt.Sprintf("role %s was created but could not be set up and could not be deleted again: %s. Delete it manually.", name, err.Error()))
+	}
+}
+
+func (r *RoleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *RoleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	out, err := r.client.IAM.GetRoleWithContext(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(data.Name.ValueString()),
+	})
+	if err != nil {
+		if isNoSuchEntity(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get role", err)...)
+		return
+	}
+	data.setRole(out.Role)
+
+	allTags, err := getRoleTags(ctx, r.client.IAM, data.Name.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get role tags", err)...)
+		return
+	}
+	data.TagsAll = tagsToMap(allTags)
+	tags := resourceTags(r.client.DefaultTags, allTags)
+	if len(tags) > 0 || !data.Tags.IsNull() {
+		data.Tags = tagsToMap(tags)
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RoleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *RoleResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var dataState *RoleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &dataState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// update trust policy
+	if !policyEquivalent(data.AssumeRolePolicy.ValueString(), dataState.AssumeRolePolicy.ValueString()) {
+		_, err := r.client.IAM.UpdateAssumeRolePolicyWithContext(ctx, &iam.UpdateAssumeRolePolicyInput{
+			RoleName:       aws.String(data.Name.ValueString()),
+			PolicyDocument: aws.String(data.AssumeRolePolicy.ValueString()),
+		})
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify role trust policy", err)...)
+			return
+		}
+	}
+
+	// update max session duration and description in place, so sessions of the role stay valid
+	if !data.MaxSessionDuration.Equal(dataState.MaxSessionDuration) || !data.Description.Equal(dataState.Description) {
+		input := &iam.UpdateRoleInput{
+			RoleName:           aws.String(data.Name.ValueString()),
+			MaxSessionDuration: aws.Int64(data.MaxSessionDuration.ValueInt64()),
+		}
+		// a removed description is cleared
+		if !data.Description.Equal(dataState.Description) {
+			input.Description = aws.String(data.Description.ValueString())
+		}
+		_, err := r.client.IAM.UpdateRoleWithContext(ctx, input)
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify role", err)...)
+			return
+		}
+	}
+
+	// update permissions boundary, a removed boundary is deleted
+	if !data.Boundary.Equal(dataState.Boundary) {
+		if err := setRolePermissionsBoundary(ctx, r.client.IAM, data.Name.ValueString(), data.Boundary.ValueString()); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify role permissions boundary", err)...)
+			return
+		}
+	}
+
+	// update tags
+	if !data.TagsAll.Equal(dataState.TagsAll) {
+		oldTags, diags := tagsFromMap(ctx, dataState.TagsAll)
+		resp.Diagnostics.Append(diags...)
+		tags, diags := tagsFromMap(ctx, data.TagsAll)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if err := updateRoleTags(ctx, r.client.IAM, data.Name.ValueString(), oldTags, tags); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify role tags", err)...)
+			return
+		}
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *RoleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *RoleResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, err := r.client.IAM.DeleteRoleWithContext(ctx, &iam.DeleteRoleInput{
+		RoleName: aws.String(data.Name.ValueString()),
+	})
+	if err != nil && !isNoSuchEntity(err) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete role", err)...)
+		return
+	}
+}
@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &STSSessionDataSource{}
+
+func NewSTSSessionDataSource() datasource.DataSource {
+	return &STSSessionDataSource{}
+}
+
+type STSSessionDataSource struct {
+	client *RgwClient
+}
+
+type STSSessionDataSourceModel struct {
+	DurationSeconds  types.Int64  `tfsdk:"duration_seconds"`
+	RoleArn          types.String `tfsdk:"role_arn"`
+	RoleSessionName  types.String `tfsdk:"role_session_name"`
+	WebIdentityToken types.String `tfsdk:"web_identity_token"`
+	Policy           types.String `tfsdk:"policy"`
+	AccessKey        types.String `tfsdk:"access_key"`
+	SecretKey        types.String `tfsdk:"secret_key"`
+	SessionToken     types.String `tfsdk:"session_token"`
+	Expiration       types.String `tfsdk:"expiration"`
+}
+
+func (d *STSSessionDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_sts_session"
+}
+
+func (d *STSSessionDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	webIdentityValidators := []validator.String{
+		stringvalidator.AlsoRequires(path.MatchRoot("role_arn"), path.MatchRoot("role_session_name"), path.MatchRoot("web_identity_token")),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Temporary credentials from the RGW STS API. Without `role_arn` a session token for the provider user is requested (`GetSessionToken`), otherwise the role is assumed with a web identity token (`AssumeRoleWithWebIdentity`). New credentials are requested on every refresh. They are stored in plain text in the state, being sensitive only hides the secret key and session token in the plan output, so anyone with access to the state can use them until they expire. Keep `duration_seconds` short.",
+
+		Attributes: map[string]schema.Attribute{
+			"duration_seconds": schema.Int64Attribute{
+				MarkdownDescription: "Validity of the credentials in seconds. Defaults to `3600`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(900, 43200),
+				},
+			},
+			"role_arn": schema.StringAttribute{
+				MarkdownDescription: "ARN of the role to assume with the web identity token",
+				Optional:            true,
+				Validators:          webIdentityValidators,
+			},
+			"role_session_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the role session, required with `role_arn`",
+				Optional:            true,
+				Validators:          webIdentityValidators,
+			},
+			"web_identity_token": schema.StringAttribute{
+				MarkdownDescription: "OpenID Connect token of the identity provider, required with `role_arn`",
+				Optional:            true,
+				Sensitive:           true,
+				Validators:          webIdentityValidators,
+			},
+			"policy": schema.StringAttribute{
+				MarkdownDescription: "Session policy (JSON) further restricting the permissions of the role, only used with `role_arn`",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("role_arn")),
+				},
+			},
+			"access_key": schema.StringAttribute{
+				MarkdownDescription: "The temporary access key",
+				Computed:            true,
+			},
+			"secret_key": schema.StringAttribute{
+				MarkdownDescription: "The temporary secret key, stored in the state",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"session_token": schema.StringAttribute{
+				MarkdownDescription: "The session token to be used with the temporary credentials, stored in the state",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"expiration": schema.StringAttribute{
+				MarkdownDescription: "Expiration time of the credentials (RFC 3339)",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *STSSessionDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *STSSessionDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data STSSessionDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	duration := int64(time.Hour / time.Second)
+	if !data.DurationSeconds.IsNull() {
+		duration = data.DurationSeconds.ValueInt64()
+	}
+
+	var credentials *sts.Credentials
+	if data.RoleArn.IsNull() {
+		out, err := d.client.STS.GetSessionTokenWithContext(ctx, &sts.GetSessionTokenInput{
+			DurationSeconds: aws.Int64(duration),
+		})
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(d.client, "could not get session token", err)...)
+			return
+		}
+		credentials = out.Credentials
+	} else {
+		input := &sts.AssumeRoleWithWebIdentityInput{
+			DurationSeconds:  aws.Int64(duration),
+			RoleArn:          aws.String(data.RoleArn.ValueString()),
+			RoleSessionName:  aws.String(data.RoleSessionName.ValueString()),
+			WebIdentityToken: aws.String(data.WebIdentityToken.ValueString()),
+		}
+		if !data.Policy.IsNull() {
+			input.Policy = aws.String(data.Policy.ValueString())
+		}
+		out, err := d.client.STS.AssumeRoleWithWebIdentityWithContext(ctx, input)
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(d.client, "could not assume role with web identity", err)...)
+			return
+		}
+		credentials = out.Credentials
+	}
+
+	if credentials == nil {
+		resp.Diagnostics.AddError("no credentials returned", "the sts api did not return any credentials")
+		return
+	}
+
+	data.AccessKey = types.StringValue(aws.StringValue(credentials.AccessKeyId))
+	data.SecretKey = types.StringValue(aws.StringValue(credentials.SecretAccessKey))
+	data.SessionToken = types.StringValue(aws.StringValue(credentials.SessionToken))
+	data.Expiration = types.StringValue(aws.TimeValue(credentials.Expiration).Format(time.RFC3339))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
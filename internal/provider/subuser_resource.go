@@ -0,0 +1,337 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &SubuserResource{}
+
+func NewSubuserResource() resource.Resource {
+	return &SubuserResource{}
+}
+
+type SubuserResource struct {
+	client *RgwClient
+}
+
+type SubuserResourceModel struct {
+	Id        types.String `tfsdk:"id"`
+	UID       types.String `tfsdk:"uid"`
+	Tenant    types.String `tfsdk:"tenant"`
+	Subuser   types.String `tfsdk:"subuser"`
+	Access    types.String `tfsdk:"access"`
+	SecretKey types.String `tfsdk:"secret_key"`
+	PurgeKeys types.Bool   `tfsdk:"purge_keys"`
+}
+
+// subuserPermissions maps the permissions reported by the admin api to the access values of requests.
+var subuserPermissions = map[string]string{
+	"read":         "read",
+	"write":        "write",
+	"read-write":   "readwrite",
+	"full-control": "full",
+}
+
+// errNoSuchSubUser is returned by the admin api for unknown subusers.
+var errNoSuchSubUser = errors.New("NoSuchSubUser")
+
+// rgwSubuserInfo describes the subusers and swift keys of a user, which go-ceph does not fully decode.
+type rgwSubuserInfo struct {
+	Subusers []struct {
+		ID          string `json:"id"`
+		Permissions string `json:"permissions"`
+	} `json:"subusers"`
+	SwiftKeys []struct {
+		User      string `json:"user"`
+		SecretKey string `json:"secret_key"`
+	} `json:"swift_keys"`
+}
+
+// uid returns the uid of the parent user qualified with the tenant.
+func (data *SubuserResourceModel) uid() string {
+	return tenantedUID(data.Tenant.ValueString(), data.UID.ValueString())
+}
+
+// subuserID returns the subuser id as reported by the admin api, "uid:subuser".
+func (data *SubuserResourceModel) subuserID() string {
+	return data.uid() + ":" + data.Subuser.ValueString()
+}
+
+func (r *SubuserResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_subuser"
+}
+
+func (r *SubuserResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Subuser of a RGW user with a generated Swift key, e.g. for Swift API clients. Set `generate_s3_credentials = false` on the parent `rgw_user` if it only serves Swift clients.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"uid": schema.StringAttribute{
+				MarkdownDescription: "The UID of the parent user.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"tenant": schema.StringAttribute{
+				MarkdownDescription: "The tenant of the parent user. If set, `uid` is qualified as `tenant$uid` for all api calls.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"subuser": schema.StringAttribute{
+				MarkdownDescription: "Name of the subuser (without the uid of the parent user).",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.NoneOf(":"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"access": schema.StringAttribute{
+				MarkdownDescription: "Access of the subuser - can be either `read`, `write`, `readwrite` or `full`. Defaults to `full`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("full"),
+				Validators: []validator.String{
+					stringvalidator.OneOf("read", "write", "readwrite", "full"),
+				},
+			},
+			"secret_key": schema.StringAttribute{
+				MarkdownDescription: "The generated Swift secret key of the subuser",
+				Computed:            true,
+				Sensitive:           true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"purge_keys": schema.BoolAttribute{
+				MarkdownDescription: "Remove the keys of the subuser when it is deleted, so no orphaned Swift keys remain. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+		},
+	}
+}
+
+func (r *SubuserResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+// getSubuserInfo fetches the subusers and swift keys of a user.
+func getSubuserInfo(ctx context.Context, api *admin.API, uid string) (rgwSubuserInfo, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/user", url.Values{
+		"uid": {uid},
+	})
+	if err != nil {
+		return rgwSubuserInfo{}, err
+	}
+
+	var info rgwSubuserInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return rgwSubuserInfo{}, fmt.Errorf("could not decode user: %w", err)
+	}
+	return info, nil
+}
+
+// setSubuser updates the model from the subuser info, it returns false if the subuser does not exist.
+func (data *SubuserResourceModel) setSubuser(info rgwSubuserInfo) bool {
+	found := false
+	for _, s := range info.Subusers {
+		if s.ID != data.subuserID() {
+			continue
+		}
+		found = true
+		if access, ok := subuserPermissions[s.Permissions]; ok {
+			data.Access = types.StringValue(access)
+		}
+	}
+	if !found {
+		return false
+	}
+
+	for _, k := range info.SwiftKeys {
+		if k.User == data.subuserID() {
+			data.SecretKey = types.StringValue(k.SecretKey)
+		}
+	}
+	if data.SecretKey.IsUnknown() {
+		data.SecretKey = types.StringNull()
+	}
+	data.Id = types.StringValue(data.subuserID())
+	return true
+}
+
+func (r *SubuserResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *SubuserResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("create subuser %s", data.subuserID()))
+	defer r.client.lockUser(data.uid())()
+
+	_, err := adminCall(ctx, r.client.Admin, http.MethodPut, "/user?subuser", url.Values{
+		"uid":             {data.uid()},
+		"subuser":         {data.Subuser.ValueString()},
+		"access":          {data.Access.ValueString()},
+		"key-type":        {"swift"},
+		"generate-secret": {"true"},
+	})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create subuser", err)...)
+		return
+	}
+
+	info, err := getSubuserInfo(ctx, r.client.Admin, data.uid())
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get subuser", err)...)
+		return
+	}
+	if !data.setSubuser(info) {
+		resp.Diagnostics.AddError("could not get subuser", fmt.Sprintf("subuser %s was not found after creation", data.subuserID()))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *SubuserResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *SubuserResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	info, err := getSubuserInfo(ctx, r.client.Admin, data.uid())
+	if err != nil {
+		if errors.Is(err, admin.ErrNoSuchUser) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get subuser", err)...)
+		return
+	}
+	if !data.setSubuser(info) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *SubuserResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *SubuserResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var dataState *SubuserResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &dataState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// only the access can be modified, purge_keys is only used on deletion
+	if !data.Access.Equal(dataState.Access) {
+		defer r.client.lockUser(data.uid())()
+		_, err := adminCall(ctx, r.client.Admin, http.MethodPost, "/user?subuser", url.Values{
+			"uid":     {data.uid()},
+			"subuser": {data.Subuser.ValueString()},
+			"access":  {data.Access.ValueString()},
+		})
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify subuser", err)...)
+			return
+		}
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *SubuserResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *SubuserResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	defer r.client.lockUser(data.uid())()
+	_, err := adminCall(ctx, r.client.Admin, http.MethodDelete, "/user?subuser", url.Values{
+		"uid":        {data.uid()},
+		"subuser":    {data.Subuser.ValueString()},
+		"purge-keys": {fmt.Sprintf("%t", data.PurgeKeys.ValueBool())},
+	})
+	// nothing to remove if the user or subuser is already gone
+	if err != nil && !errors.Is(err, admin.ErrNoSuchUser) && !errors.Is(err, errNoSuchSubUser) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete subuser", err)...)
+		return
+	}
+}
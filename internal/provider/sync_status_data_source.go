@@ -0,0 +1,367 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &SyncStatusDataSource{}
+
+func NewSyncStatusDataSource() datasource.DataSource {
+	return &SyncStatusDataSource{}
+}
+
+type SyncStatusDataSource struct {
+	client *RgwClient
+}
+
+type SyncStatusDataSourceModel struct {
+	SourceZone             types.String `tfsdk:"source_zone"`
+	SourceEndpoint         types.String `tfsdk:"source_endpoint"`
+	Concurrency            types.Int64  `tfsdk:"concurrency"`
+	MetadataStatus         types.String `tfsdk:"metadata_status"`
+	MetadataShards         types.Int64  `tfsdk:"metadata_shards"`
+	MetadataBehindShards   types.Int64  `tfsdk:"metadata_behind_shards"`
+	MetadataFullSyncShards types.Int64  `tfsdk:"metadata_full_sync_shards"`
+	DataStatus             types.String `tfsdk:"data_status"`
+	DataShards             types.Int64  `tfsdk:"data_shards"`
+	DataBehindShards       types.Int64  `tfsdk:"data_behind_shards"`
+	DataFullSyncShards     types.Int64  `tfsdk:"data_full_sync_shards"`
+	LagSeconds             types.Int64  `tfsdk:"lag_seconds"`
+	CaughtUp               types.Bool   `tfsdk:"caught_up"`
+}
+
+// rgwSyncMarker is the sync position of a log shard.
+type rgwSyncMarker struct {
+	State     int    `json:"state"`
+	Marker    string `json:"marker"`
+	Timestamp string `json:"timestamp"`
+}
+
+// rgwSyncMarkerIncremental is the state of shards which finished the full sync.
+const rgwSyncMarkerIncremental = 1
+
+// rgwSyncStatus is the metadata or data sync status of the zone.
+type rgwSyncStatus struct {
+	Info struct {
+		Status    string `json:"status"`
+		NumShards int    `json:"num_shards"`
+		Period    string `json:"period"`
+	} `json:"info"`
+	Markers []struct {
+		Key int           `json:"key"`
+		Val rgwSyncMarker `json:"val"`
+	} `json:"markers"`
+}
+
+// rgwLogShardInfo is the position of a log shard of the source zone.
+type rgwLogShardInfo struct {
+	Marker string `json:"marker"`
+}
+
+// syncProgress summarizes the sync status of the shards of a log.
+type syncProgress struct {
+	Status         string
+	Shards         int
+	FullSyncShards int
+	BehindShards   int
+	// Oldest is the time of the last applied change of the shard furthest behind.
+	Oldest time.Time
+}
+
+// syncTimestampLayouts are the formats of sync marker timestamps of the Ceph releases.
+var syncTimestampLayouts = []string{"2006-01-02T15:04:05.999999999Z", "2006-01-02 15:04:05.999999999Z"}
+
+func parseSyncTimestamp(timestamp string) (time.Time, bool) {
+	for _, layout := range syncTimestampLayouts {
+		if t, err := time.Parse(layout, timestamp); err == nil && t.Unix() > 0 {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// getSyncStatus fetches the metadata (logType `metadata`) or data (logType `data`) sync status.
+// Zones which do not sync the log, e.g. the metadata master, return admin.ErrNoSuchKey.
+func getSyncStatus(ctx context.Context, api *admin.API, logType string, sourceZone string) (rgwSyncStatus, error) {
+	args := url.Values{
+		"type":   {logType},
+		"status": {""},
+	}
+	if logType == "data" {
+		args.Set("source-zone", sourceZone)
+	}
+	body, err := adminCall(ctx, api, http.MethodGet, "/log", args)
+	if err != nil {
+		return rgwSyncStatus{}, err
+	}
+
+	var status struct {
+		Status rgwSyncStatus `json:"status"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return rgwSyncStatus{}, fmt.Errorf("could not decode %s sync status: %w", logType, err)
+	}
+	return status.Status, nil
+}
+
+// getLogShardInfo fetches the current position of a log shard.
+func getLogShardInfo(ctx context.Context, api *admin.API, logType string, shard int, period string) (rgwLogShardInfo, error) {
+	args := url.Values{
+		"type": {logType},
+		"id":   {strconv.Itoa(shard)},
+		"info": {""},
+	}
+	if period != "" {
+		args.Set("period", period)
+	}
+	body, err := adminCall(ctx, api, http.MethodGet, "/log", args)
+	if err != nil {
+		return rgwLogShardInfo{}, err
+	}
+
+	var info rgwLogShardInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return rgwLogShardInfo{}, fmt.Errorf("could not decode %s log shard %d: %w", logType, shard, err)
+	}
+	return info, nil
+}
+
+// syncProgressOf summarizes a sync status. Shards in full sync are always behind, shards in
+// incremental sync are behind if the log of the source zone has advanced past their marker.
+// The source is only queried if source is not nil.
+func syncProgressOf(ctx context.Context, source *admin.API, logType string, status rgwSyncStatus, workers int) (syncProgress, error) {
+	progress := syncProgress{Status: status.Info.Status, Shards: status.Info.NumShards}
+
+	var mu sync.Mutex
+	behind := func(marker rgwSyncMarker) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.BehindShards++
+		if t, ok := parseSyncTimestamp(marker.Timestamp); ok && (progress.Oldest.IsZero() || t.Before(progress.Oldest)) {
+			progress.Oldest = t
+		}
+	}
+
+	markers := make(map[string]rgwSyncMarker, len(status.Markers))
+	var incremental []string
+	for _, m := range status.Markers {
+		if m.Val.State != rgwSyncMarkerIncremental {
+			progress.FullSyncShards++
+			behind(m.Val)
+			continue
+		}
+		shard := strconv.Itoa(m.Key)
+		markers[shard] = m.Val
+		incremental = append(incremental, shard)
+	}
+
+	if source == nil {
+		return progress, nil
+	}
+
+	errs := forEachConcurrent(ctx, incremental, workers, func(ctx context.Context, shard string) error {
+		n, _ := strconv.Atoi(shard)
+		info, err := getLogShardInfo(ctx, source, logType, n, status.Info.Period)
+		if err != nil {
+			return err
+		}
+		// markers of a log shard sort in the order of their entries
+		if info.Marker > markers[shard].Marker {
+			behind(markers[shard])
+		}
+		return nil
+	})
+	if len(errs) > 0 {
+		return progress, fmt.Errorf("could not get %s log shards of the source zone: %s", logType, joinKeyErrors(errs))
+	}
+
+	return progress, nil
+}
+
+func (d *SyncStatusDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_sync_status"
+}
+
+func (d *SyncStatusDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Multisite sync status of the zone the gateway is serving, like `radosgw-admin sync status`. Intended for preconditions which gate destructive operations on the secondary zone having caught up. Shards still in full sync are always counted as behind, shards in incremental sync only if `source_endpoint` is set.",
+
+		Attributes: map[string]schema.Attribute{
+			"source_zone": schema.StringAttribute{
+				MarkdownDescription: "ID or name of the zone the data is synced from",
+				Required:            true,
+			},
+			"source_endpoint": schema.StringAttribute{
+				MarkdownDescription: "Endpoint of a gateway of the source zone, accessed with the credentials of the provider, e.g. the replicated system user. If set, the markers of shards in incremental sync are compared with the logs of the source zone. The metadata log is compared with the same endpoint, so use a gateway of the metadata master zone.",
+				Optional:            true,
+			},
+			"concurrency": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of concurrent requests to the source zone. Defaults to `10`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"metadata_status": schema.StringAttribute{
+				MarkdownDescription: "State of the metadata sync, e.g. `sync`. Null on the metadata master zone, which does not sync metadata.",
+				Computed:            true,
+			},
+			"metadata_shards": schema.Int64Attribute{
+				MarkdownDescription: "Number of metadata log shards",
+				Computed:            true,
+			},
+			"metadata_behind_shards": schema.Int64Attribute{
+				MarkdownDescription: "Number of metadata log shards behind the source zone",
+				Computed:            true,
+			},
+			"metadata_full_sync_shards": schema.Int64Attribute{
+				MarkdownDescription: "Number of metadata log shards still in full sync",
+				Computed:            true,
+			},
+			"data_status": schema.StringAttribute{
+				MarkdownDescription: "State of the data sync from the source zone, e.g. `sync`",
+				Computed:            true,
+			},
+			"data_shards": schema.Int64Attribute{
+				MarkdownDescription: "Number of data log shards",
+				Computed:            true,
+			},
+			"data_behind_shards": schema.Int64Attribute{
+				MarkdownDescription: "Number of data log shards behind the source zone",
+				Computed:            true,
+			},
+			"data_full_sync_shards": schema.Int64Attribute{
+				MarkdownDescription: "Number of data log shards still in full sync",
+				Computed:            true,
+			},
+			"lag_seconds": schema.Int64Attribute{
+				MarkdownDescription: "Seconds since the last applied change of the shard furthest behind, an upper bound of the replication lag. `0` if no shard is behind.",
+				Computed:            true,
+			},
+			"caught_up": schema.BoolAttribute{
+				MarkdownDescription: "Whether metadata and data sync are in the `sync` state without shards behind",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *SyncStatusDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *SyncStatusDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data SyncStatusDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	workers := defaultConcurrency
+	if !data.Concurrency.IsNull() {
+		workers = int(data.Concurrency.ValueInt64())
+	}
+
+	// the source zone is accessed with the credentials of the provider
+	var source *admin.API
+	if !data.SourceEndpoint.IsNull() {
+		var err error
+		source, err = admin.New(data.SourceEndpoint.ValueString(), d.client.Admin.AccessKey, d.client.Admin.SecretKey, nil)
+		if err != nil {
+			resp.Diagnostics.AddError("could not create rgw admin client for the source zone", err.Error())
+			return
+		}
+	}
+
+	caughtUp := true
+	var oldest time.Time
+	track := func(progress syncProgress) {
+		caughtUp = caughtUp && progress.Status == "sync" && progress.BehindShards == 0
+		if !progress.Oldest.IsZero() && (oldest.IsZero() || progress.Oldest.Before(oldest)) {
+			oldest = progress.Oldest
+		}
+	}
+
+	// metadata is not synced by the metadata master zone
+	mdStatus, err := getSyncStatus(ctx, d.client.Admin, "metadata", "")
+	switch {
+	case errors.Is(err, admin.ErrNoSuchKey):
+		data.MetadataStatus = types.StringNull()
+		data.MetadataShards = types.Int64Value(0)
+		data.MetadataBehindShards = types.Int64Value(0)
+		data.MetadataFullSyncShards = types.Int64Value(0)
+	case err != nil:
+		resp.Diagnostics.Append(errorDiagnostics(d.client, "could not get metadata sync status", err)...)
+		return
+	default:
+		progress, err := syncProgressOf(ctx, source, "metadata", mdStatus, workers)
+		if err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(d.client, "could not get metadata sync status", err)...)
+			return
+		}
+		track(progress)
+		data.MetadataStatus = types.StringValue(progress.Status)
+		data.MetadataShards = types.Int64Value(int64(progress.Shards))
+		data.MetadataBehindShards = types.Int64Value(int64(progress.BehindShards))
+		data.MetadataFullSyncShards = types.Int64Value(int64(progress.FullSyncShards))
+	}
+
+	dataStatus, err := getSyncStatus(ctx, d.client.Admin, "data", data.SourceZone.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(d.client, "could not get data sync status", err)...)
+		return
+	}
+	progress, err := syncProgressOf(ctx, source, "data", dataStatus, workers)
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(d.client, "could not get data sync status", err)...)
+		return
+	}
+	track(progress)
+	data.DataStatus = types.StringValue(progress.Status)
+	data.DataShards = types.Int64Value(int64(progress.Shards))
+	data.DataBehindShards = types.Int64Value(int64(progress.BehindShards))
+	data.DataFullSyncShards = types.Int64Value(int64(progress.FullSyncShards))
+
+	lag := int64(0)
+	if !oldest.IsZero() {
+		lag = int64(time.Since(oldest).Seconds())
+	}
+	data.LagSeconds = types.Int64Value(lag)
+	data.CaughtUp = types.BoolValue(caughtUp)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
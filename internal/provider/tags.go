@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// mergeTags merges resource tags over the provider default tags.
+func mergeTags(defaultTags, tags map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaultTags)+len(tags))
+	for k, v := range defaultTags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return merged
+}
+
+// resourceTags returns the tags not inherited from the provider default tags.
+func resourceTags(defaultTags, allTags map[string]string) map[string]string {
+	tags := make(map[string]string)
+	for k, v := range allTags {
+		if d, ok := defaultTags[k]; ok && d == v {
+			continue
+		}
+		tags[k] = v
+	}
+	return tags
+}
+
+// modifyTagsAllPlan sets tags_all to the planned tags merged over the provider default tags.
+func modifyTagsAllPlan(ctx context.Context, defaultTags map[string]string, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to do on destroy
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var tags types.Map
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("tags"), &tags)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// tags_all can only be computed once all tags are known
+	unknown := tags.IsUnknown()
+	for _, v := range tags.Elements() {
+		unknown = unknown || v.IsUnknown()
+	}
+	if unknown {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("tags_all"), types.MapUnknown(types.StringType))...)
+		return
+	}
+
+	planTags, diags := tagsFromMap(ctx, tags)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("tags_all"), tagsToMap(mergeTags(defaultTags, planTags)))...)
+}
+
+func tagsFromMap(ctx context.Context, m types.Map) (map[string]string, diag.Diagnostics) {
+	tags := make(map[string]string)
+	if m.IsNull() || m.IsUnknown() {
+		return tags, nil
+	}
+	diags := m.ElementsAs(ctx, &tags, false)
+	return tags, diags
+}
+
+func tagsToMap(tags map[string]string) types.Map {
+	elements := make(map[string]attr.Value, len(tags))
+	for k, v := range tags {
+		elements[k] = types.StringValue(v)
+	}
+	return types.MapValueMust(types.StringType, elements)
+}
+
+// getBucketTags returns the tag set of a bucket, an empty map if none is set.
+func getBucketTags(ctx context.Context, client *s3.Client, bucket string) (map[string]string, error) {
+	tags := make(map[string]string)
+	s3res, err := client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "NoSuchTagSet" {
+			return tags, nil
+		}
+		return nil, err
+	}
+	for _, t := range s3res.TagSet {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return tags, nil
+}
+
+// putBucketTags replaces the tag set of a bucket, deleting it if tags is empty.
+func putBucketTags(ctx context.Context, client *s3.Client, bucket string, tags map[string]string) error {
+	if len(tags) == 0 {
+		_, err := client.DeleteBucketTagging(ctx, &s3.DeleteBucketTaggingInput{
+			Bucket: aws.String(bucket),
+		})
+		return err
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tagSet := make([]s3types.Tag, len(keys))
+	for i, k := range keys {
+		tagSet[i] = s3types.Tag{Key: aws.String(k), Value: aws.String(tags[k])}
+	}
+
+	_, err := client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucket),
+		Tagging: &s3types.Tagging{TagSet: tagSet},
+	})
+	return err
+}
+
+// getRoleTags returns the tags of a role.
+func getRoleTags(ctx context.Context, client *iam.IAM, role string) (map[string]string, error) {
+	tags := make(map[string]string)
+	err := client.ListRoleTagsPagesWithContext(ctx, &iam.ListRoleTagsInput{
+		RoleName: aws.String(role),
+	}, func(page *iam.ListRoleTagsOutput, lastPage bool) bool {
+		for _, t := range page.Tags {
+			tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+		}
+		return true
+	})
+	return tags, err
+}
+
+// updateRoleTags removes the tags of a role which are not in tags and sets all others.
+func updateRoleTags(ctx context.Context, client *iam.IAM, role string, oldTags, tags map[string]string) error {
+	var removed []*string
+	for k := range oldTags {
+		if _, ok := tags[k]; !ok {
+			removed = append(removed, aws.String(k))
+		}
+	}
+	if len(removed) > 0 {
+		_, err := client.UntagRoleWithContext(ctx, &iam.UntagRoleInput{
+			RoleName: aws.String(role),
+			TagKeys:  removed,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if old, ok := oldTags[k]; !ok || old != v {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	tagSet := make([]*iam.Tag, len(keys))
+	for i, k := range keys {
+		tagSet[i] = &iam.Tag{Key: aws.String(k), Value: aws.String(tags[k])}
+	}
+
+	_, err := client.TagRoleWithContext(ctx, &iam.TagRoleInput{
+		RoleName: aws.String(role),
+		Tags:     tagSet,
+	})
+	return err
+}
@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tenantedUID qualifies a user ID with a tenant as "tenant$uid". IDs which are
+// already qualified with the tenant are returned unchanged.
+func tenantedUID(tenant, uid string) string {
+	if tenant == "" || strings.HasPrefix(uid, tenant+"$") {
+		return uid
+	}
+	return fmt.Sprintf("%s$%s", tenant, uid)
+}
+
+// tenantedBucket qualifies a bucket name with a tenant as "tenant/bucket" as expected
+// by the admin api. Names which are already qualified are returned unchanged.
+func tenantedBucket(tenant, bucket string) string {
+	if tenant == "" || strings.HasPrefix(bucket, tenant+"/") {
+		return bucket
+	}
+	return fmt.Sprintf("%s/%s", tenant, bucket)
+}
+
+// tenantedS3Bucket qualifies a bucket name with a tenant as "tenant:bucket" as expected
+// by the s3 api. Names which are already qualified are returned unchanged.
+func tenantedS3Bucket(tenant, bucket string) string {
+	if tenant == "" || strings.HasPrefix(bucket, tenant+":") {
+		return bucket
+	}
+	return fmt.Sprintf("%s:%s", tenant, bucket)
+}
@@ -0,0 +1,404 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	shareAccessReadOnly  = "ro"
+	shareAccessReadWrite = "rw"
+)
+
+var shareReadActions = []string{
+	"s3:GetBucketLocation",
+	"s3:ListBucket",
+	"s3:GetObject",
+}
+
+var shareWriteActions = []string{
+	"s3:ListBucketMultipartUploads",
+	"s3:ListMultipartUploadParts",
+	"s3:PutObject",
+	"s3:DeleteObject",
+	"s3:AbortMultipartUpload",
+}
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &TenantBucketShareResource{}
+
+func NewTenantBucketShareResource() resource.Resource {
+	return &TenantBucketShareResource{}
+}
+
+type TenantBucketShareResource struct {
+	client *RgwClient
+}
+
+type TenantBucketShareResourceModel struct {
+	Id        types.String         `tfsdk:"id"`
+	Bucket    types.String         `tfsdk:"bucket"`
+	Tenant    types.String         `tfsdk:"tenant"`
+	SidPrefix types.String         `tfsdk:"sid_prefix"`
+	Consumers []ShareConsumerModel `tfsdk:"consumers"`
+	Policy    types.String         `tfsdk:"policy"`
+}
+
+type ShareConsumerModel struct {
+	Tenant types.String `tfsdk:"tenant"`
+	UID    types.String `tfsdk:"uid"`
+	Access types.String `tfsdk:"access"`
+}
+
+func (r *TenantBucketShareResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_tenant_bucket_share"
+}
+
+func (r *TenantBucketShareResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Shares a bucket with users of other tenants. The resource generates read-only and read-write statements with tenant-qualified principals and merges them into the bucket policy by `Sid` like `rgw_bucket_policy_attachment`. Access is granted by the bucket policy only, ACLs of the bucket are not modified. Upon deletion, the statements are removed.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"bucket": schema.StringAttribute{
+				MarkdownDescription: "Bucket Name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"tenant": schema.StringAttribute{
+				MarkdownDescription: "The tenant of the bucket. If set, the bucket is addressed as `tenant:bucket`.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"sid_prefix": schema.StringAttribute{
+				MarkdownDescription: "Prefix of the statement Sids, the access level `ReadOnly` or `ReadWrite` is appended. Must be unique within the bucket policy. Defaults to `TenantShare`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("TenantShare"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"consumers": schema.SetNestedAttribute{
+				MarkdownDescription: "The users the bucket is shared with",
+				Required:            true,
+				Validators: []validator.Set{
+					setvalidator.SizeAtLeast(1),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"tenant": schema.StringAttribute{
+							MarkdownDescription: "The tenant of the user",
+							Optional:            true,
+						},
+						"uid": schema.StringAttribute{
+							MarkdownDescription: "The user ID (without tenant)",
+							Required:            true,
+						},
+						"access": schema.StringAttribute{
+							MarkdownDescription: "Access level - can be either `ro` (list and read objects) or `rw` (additionally write and delete objects)",
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf(shareAccessReadOnly, shareAccessReadWrite),
+							},
+						},
+					},
+				},
+			},
+			"policy": schema.StringAttribute{
+				MarkdownDescription: "The generated policy statements (JSON)",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *TenantBucketShareResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	// the resource can not be used without the s3 api
+	resp.Diagnostics.Append(requireS3(client, "rgw_tenant_bucket_share")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.client = client
+}
+
+// s3Bucket returns the bucket name as addressed via s3.
+func (data *TenantBucketShareResourceModel) s3Bucket() string {
+	return tenantedS3Bucket(data.Tenant.ValueString(), data.Bucket.ValueString())
+}
+
+// sids returns the Sids of the statements managed by the share.
+func (data *TenantBucketShareResourceModel) sids() []string {
+	return []string{data.SidPrefix.ValueString() + "ReadOnly", data.SidPrefix.ValueString() + "ReadWrite"}
+}
+
+// statements generates the policy statements for the consumers, one per access level.
+func (data *TenantBucketShareResourceModel) statements() []map[string]interface{} {
+	principals := map[string][]string{}
+	for _, c := range data.Consumers {
+		access := c.Access.ValueString()
+		principals[access] = append(principals[access], principalARN(c.Tenant.ValueString(), c.UID.ValueString()))
+	}
+
+	bucketARN := fmt.Sprintf("arn:aws:s3::%s:%s", data.Tenant.ValueString(), data.Bucket.ValueString())
+	resources := []string{bucketARN, bucketARN + "/*"}
+	sids := data.sids()
+
+	var statements []map[string]interface{}
+	if arns := principals[shareAccessReadOnly]; len(arns) > 0 {
+		sort.Strings(arns)
+		statements = append(statements, map[string]interface{}{
+			"Sid":       sids[0],
+			"Effect":    "Allow",
+			"Principal": map[string]interface{}{"AWS": arns},
+			"Action":    shareReadActions,
+			"Resource":  resources,
+		})
+	}
+	if arns := principals[shareAccessReadWrite]; len(arns) > 0 {
+		sort.Strings(arns)
+		statements = append(statements, map[string]interface{}{
+			"Sid":       sids[1],
+			"Effect":    "Allow",
+			"Principal": map[string]interface{}{"AWS": arns},
+			"Action":    append(append([]string{}, shareReadActions...), shareWriteActions...),
+			"Resource":  resources,
+		})
+	}
+	return statements
+}
+
+// apply merges the generated statements into the bucket policy, replacing the statements in owned.
+func (r *TenantBucketShareResource) apply(ctx context.Context, data *TenantBucketShareResourceModel, owned []string) error {
+	statements := data.statements()
+
+	policy, err := json.Marshal(statements)
+	if err != nil {
+		return err
+	}
+	data.Policy = types.StringValue(string(policy))
+
+	tflog.Info(ctx, fmt.Sprintf("share bucket %s with %d users", data.s3Bucket(), len(data.Consumers)))
+
+	return attachPolicyStatements(ctx, r.client, data.s3Bucket(), owned, statements)
+}
+
+func (r *TenantBucketShareResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *TenantBucketShareResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.apply(ctx, data, nil); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not share bucket", err)...)
+		return
+	}
+
+	data.Id = types.StringValue(data.s3Bucket())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *TenantBucketShareResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *TenantBucketShareResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	policy, err := getBucketPolicy(ctx, r.client.S3, data.s3Bucket())
+	if err != nil {
+		// bucket was deleted outside of terraform
+		if isNoSuchBucket(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get bucket policy", err)...)
+		return
+	}
+
+	doc, err := parsePolicyDocument(policy)
+	if err != nil {
+		resp.Diagnostics.AddError("could not parse bucket policy", err.Error())
+		return
+	}
+
+	// rebuild the consumers from the statements to detect changes outside of terraform
+	var found []map[string]interface{}
+	var consumers []ShareConsumerModel
+	for i, sid := range data.sids() {
+		st, ok := doc.statement(sid)
+		if !ok {
+			continue
+		}
+		found = append(found, st)
+
+		access := shareAccessReadOnly
+		if i == 1 {
+			access = shareAccessReadWrite
+		}
+		for _, arn := range statementPrincipals(st) {
+			tenant, uid, ok := parsePrincipalARN(arn)
+			if !ok {
+				resp.Diagnostics.AddWarning("unexpected principal", fmt.Sprintf("statement %s of bucket %s contains the principal '%s' which is not a user", sid, data.s3Bucket(), arn))
+				continue
+			}
+			consumer := ShareConsumerModel{
+				Tenant: types.StringNull(),
+				UID:    types.StringValue(uid),
+				Access: types.StringValue(access),
+			}
+			if tenant != "" {
+				consumer.Tenant = types.StringValue(tenant)
+			}
+			consumers = append(consumers, consumer)
+		}
+	}
+	if len(found) == 0 {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// keep the state if the statements are semantically equal
+	statements, err := json.Marshal(found)
+	if err != nil {
+		resp.Diagnostics.AddError("could not encode statements", err.Error())
+		return
+	}
+	if !policyEquivalent(string(statements), data.Policy.ValueString()) {
+		data.Policy = types.StringValue(string(statements))
+		data.Consumers = consumers
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *TenantBucketShareResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *TenantBucketShareResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.apply(ctx, data, data.sids()); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify bucket share", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *TenantBucketShareResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *TenantBucketShareResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := modifyBucketPolicy(ctx, r.client, data.s3Bucket(), func(doc *policyDocument) error {
+		doc.removeStatements(data.sids())
+		return nil
+	})
+	if err != nil && !isNoSuchBucket(err) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not remove bucket share", err)...)
+		return
+	}
+}
+
+// statementPrincipals returns the AWS principals of a statement.
+func statementPrincipals(statement map[string]interface{}) []string {
+	principal, _ := statement["Principal"].(map[string]interface{})
+	switch aws := principal["AWS"].(type) {
+	case string:
+		return []string{aws}
+	case []interface{}:
+		arns := make([]string, 0, len(aws))
+		for _, v := range aws {
+			if s, ok := v.(string); ok {
+				arns = append(arns, s)
+			}
+		}
+		return arns
+	}
+	return nil
+}
+
+// parsePrincipalARN splits a user principal "arn:aws:iam::<tenant>:user/<uid>" into tenant and uid.
+func parsePrincipalARN(arn string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(arn, "arn:aws:iam::")
+	if !ok {
+		return "", "", false
+	}
+	tenant, user, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", "", false
+	}
+	uid, ok := strings.CutPrefix(user, "user/")
+	if !ok || uid == "" {
+		return "", "", false
+	}
+	return tenant, uid, true
+}
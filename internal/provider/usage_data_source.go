@@ -0,0 +1,331 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &UsageDataSource{}
+
+func NewUsageDataSource() datasource.DataSource {
+	return &UsageDataSource{}
+}
+
+type UsageDataSource struct {
+	client *RgwClient
+}
+
+type UsageDataSourceModel struct {
+	Id           types.String      `tfsdk:"id"`
+	UID          types.String      `tfsdk:"uid"`
+	Start        types.String      `tfsdk:"start"`
+	End          types.String      `tfsdk:"end"`
+	GroupBy      types.String      `tfsdk:"group_by"`
+	Entries      []UsageEntryModel `tfsdk:"entries"`
+	Totals       []UsageTotalModel `tfsdk:"totals"`
+	RenderedJSON types.String      `tfsdk:"rendered_json"`
+}
+
+type UsageEntryModel struct {
+	User          types.String `tfsdk:"user"`
+	Bucket        types.String `tfsdk:"bucket"`
+	Time          types.String `tfsdk:"time"`
+	Category      types.String `tfsdk:"category"`
+	BytesSent     types.Int64  `tfsdk:"bytes_sent"`
+	BytesReceived types.Int64  `tfsdk:"bytes_received"`
+	Ops           types.Int64  `tfsdk:"ops"`
+	SuccessfulOps types.Int64  `tfsdk:"successful_ops"`
+}
+
+type UsageTotalModel struct {
+	Key           types.String `tfsdk:"key"`
+	BytesSent     types.Int64  `tfsdk:"bytes_sent"`
+	BytesReceived types.Int64  `tfsdk:"bytes_received"`
+	Ops           types.Int64  `tfsdk:"ops"`
+	SuccessfulOps types.Int64  `tfsdk:"successful_ops"`
+}
+
+// usageRecord is a usage entry of one category of a bucket in one hour, as exported in rendered_json.
+type usageRecord struct {
+	User          string `json:"user"`
+	Bucket        string `json:"bucket"`
+	Time          string `json:"time"`
+	Category      string `json:"category"`
+	BytesSent     uint64 `json:"bytes_sent"`
+	BytesReceived uint64 `json:"bytes_received"`
+	Ops           uint64 `json:"ops"`
+	SuccessfulOps uint64 `json:"successful_ops"`
+}
+
+// usageTotal is the sum of the usage records with the same key, as exported in rendered_json.
+type usageTotal struct {
+	Key           string `json:"key"`
+	BytesSent     uint64 `json:"bytes_sent"`
+	BytesReceived uint64 `json:"bytes_received"`
+	Ops           uint64 `json:"ops"`
+	SuccessfulOps uint64 `json:"successful_ops"`
+}
+
+func (d *UsageDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_usage"
+}
+
+func (d *UsageDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	timeValidators := []validator.String{
+		stringvalidator.RegexMatches(usageTimeRegexp, "must be formatted as `YYYY-MM-DD` or `YYYY-MM-DD HH:MM:SS`"),
+	}
+
+	usageAttributes := map[string]schema.Attribute{
+		"bytes_sent": schema.Int64Attribute{
+			MarkdownDescription: "Bytes sent by the gateway",
+			Computed:            true,
+		},
+		"bytes_received": schema.Int64Attribute{
+			MarkdownDescription: "Bytes received by the gateway",
+			Computed:            true,
+		},
+		"ops": schema.Int64Attribute{
+			MarkdownDescription: "Number of operations",
+			Computed:            true,
+		},
+		"successful_ops": schema.Int64Attribute{
+			MarkdownDescription: "Number of successful operations",
+			Computed:            true,
+		},
+	}
+
+	entryAttributes := map[string]schema.Attribute{
+		"user": schema.StringAttribute{
+			MarkdownDescription: "The user the usage is accounted to",
+			Computed:            true,
+		},
+		"bucket": schema.StringAttribute{
+			MarkdownDescription: "The bucket of the operations, `-` for operations without a bucket",
+			Computed:            true,
+		},
+		"time": schema.StringAttribute{
+			MarkdownDescription: "Start of the hour the usage is logged for",
+			Computed:            true,
+		},
+		"category": schema.StringAttribute{
+			MarkdownDescription: "Category of the operations, e.g. `get_obj` or `put_obj`",
+			Computed:            true,
+		},
+	}
+	totalAttributes := map[string]schema.Attribute{
+		"key": schema.StringAttribute{
+			MarkdownDescription: "The bucket, category or user of the total depending on `group_by`",
+			Computed:            true,
+		},
+	}
+	for name, attr := range usageAttributes {
+		entryAttributes[name] = attr
+		totalAttributes[name] = attr
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Usage log of the RGW, e.g. for billing. Requires the usage log to be enabled with `rgw_enable_usage_log`. The entries can be summed up by bucket, category or user and are rendered as JSON for export.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"uid": schema.StringAttribute{
+				MarkdownDescription: "The user to get the usage for. If not set, the usage of all users is returned.",
+				Optional:            true,
+			},
+			"start": schema.StringAttribute{
+				MarkdownDescription: "Usage starting at this time (`YYYY-MM-DD` or `YYYY-MM-DD HH:MM:SS`)",
+				Optional:            true,
+				Validators:          timeValidators,
+			},
+			"end": schema.StringAttribute{
+				MarkdownDescription: "Usage up to this time (`YYYY-MM-DD` or `YYYY-MM-DD HH:MM:SS`)",
+				Optional:            true,
+				Validators:          timeValidators,
+			},
+			"group_by": schema.StringAttribute{
+				MarkdownDescription: "Sum up the entries in `totals` by `bucket`, `category` or `user`. If not set, `totals` is empty.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf("bucket", "category", "user"),
+				},
+			},
+			"entries": schema.ListNestedAttribute{
+				MarkdownDescription: "The usage of each category of a bucket per hour",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: entryAttributes,
+				},
+			},
+			"totals": schema.ListNestedAttribute{
+				MarkdownDescription: "The sum of the entries grouped by `group_by`, sorted by key",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: totalAttributes,
+				},
+			},
+			"rendered_json": schema.StringAttribute{
+				MarkdownDescription: "The entries and totals as JSON object with the keys `entries` and `totals`",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *UsageDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+// usageRecords flattens the usage log into one record per category, bucket and hour.
+func usageRecords(usage admin.Usage) []usageRecord {
+	var records []usageRecord
+	for _, e := range usage.Entries {
+		for _, b := range e.Buckets {
+			for _, c := range b.Categories {
+				records = append(records, usageRecord{
+					User:          e.User,
+					Bucket:        b.Bucket,
+					Time:          b.Time,
+					Category:      c.Category,
+					BytesSent:     c.BytesSent,
+					BytesReceived: c.BytesReceived,
+					Ops:           c.Ops,
+					SuccessfulOps: c.SuccessfulOps,
+				})
+			}
+		}
+	}
+	return records
+}
+
+// sumUsageRecords sums up the records by the bucket, category or user, sorted by key.
+func sumUsageRecords(records []usageRecord, groupBy string) []usageTotal {
+	byKey := make(map[string]*usageTotal)
+	for _, r := range records {
+		var key string
+		switch groupBy {
+		case "bucket":
+			key = r.Bucket
+		case "category":
+			key = r.Category
+		default:
+			key = r.User
+		}
+
+		total, ok := byKey[key]
+		if !ok {
+			total = &usageTotal{Key: key}
+			byKey[key] = total
+		}
+		total.BytesSent += r.BytesSent
+		total.BytesReceived += r.BytesReceived
+		total.Ops += r.Ops
+		total.SuccessfulOps += r.SuccessfulOps
+	}
+
+	totals := make([]usageTotal, 0, len(byKey))
+	for _, total := range byKey {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Key < totals[j].Key
+	})
+	return totals
+}
+
+func (d *UsageDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data UsageDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	showEntries := true
+	showSummary := false
+	usage, err := d.client.Admin.GetUsage(ctx, admin.Usage{
+		UserID:      data.UID.ValueString(),
+		Start:       data.Start.ValueString(),
+		End:         data.End.ValueString(),
+		ShowEntries: &showEntries,
+		ShowSummary: &showSummary,
+	})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(d.client, "could not get usage", err)...)
+		return
+	}
+
+	records := usageRecords(usage)
+	totals := []usageTotal{}
+	if !data.GroupBy.IsNull() {
+		totals = sumUsageRecords(records, data.GroupBy.ValueString())
+	}
+
+	data.Entries = make([]UsageEntryModel, len(records))
+	for i, r := range records {
+		data.Entries[i] = UsageEntryModel{
+			User:          types.StringValue(r.User),
+			Bucket:        types.StringValue(r.Bucket),
+			Time:          types.StringValue(r.Time),
+			Category:      types.StringValue(r.Category),
+			BytesSent:     types.Int64Value(int64(r.BytesSent)),
+			BytesReceived: types.Int64Value(int64(r.BytesReceived)),
+			Ops:           types.Int64Value(int64(r.Ops)),
+			SuccessfulOps: types.Int64Value(int64(r.SuccessfulOps)),
+		}
+	}
+	data.Totals = make([]UsageTotalModel, len(totals))
+	for i, t := range totals {
+		data.Totals[i] = UsageTotalModel{
+			Key:           types.StringValue(t.Key),
+			BytesSent:     types.Int64Value(int64(t.BytesSent)),
+			BytesReceived: types.Int64Value(int64(t.BytesReceived)),
+			Ops:           types.Int64Value(int64(t.Ops)),
+			SuccessfulOps: types.Int64Value(int64(t.SuccessfulOps)),
+		}
+	}
+
+	if records == nil {
+		records = []usageRecord{}
+	}
+	rendered, err := json.Marshal(struct {
+		Entries []usageRecord `json:"entries"`
+		Totals  []usageTotal  `json:"totals"`
+	}{records, totals})
+	if err != nil {
+		resp.Diagnostics.AddError("could not render usage", err.Error())
+		return
+	}
+	data.RenderedJSON = types.StringValue(string(rendered))
+	data.Id = types.StringValue(fmt.Sprintf("%s:%s:%s", data.UID.ValueString(), data.Start.ValueString(), data.End.ValueString()))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
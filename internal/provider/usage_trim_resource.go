@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const usageTimeFormat = "2006-01-02 15:04:05"
+
+var usageTimeRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}( \d{2}:\d{2}:\d{2})?$`)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &UsageTrimResource{}
+var _ resource.ResourceWithValidateConfig = &UsageTrimResource{}
+
+func NewUsageTrimResource() resource.Resource {
+	return &UsageTrimResource{}
+}
+
+type UsageTrimResource struct {
+	client *RgwClient
+}
+
+type UsageTrimResourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	UID           types.String `tfsdk:"uid"`
+	Start         types.String `tfsdk:"start"`
+	End           types.String `tfsdk:"end"`
+	OlderThanDays types.Int64  `tfsdk:"older_than_days"`
+	RemoveAll     types.Bool   `tfsdk:"remove_all"`
+	Triggers      types.Map    `tfsdk:"triggers"`
+	TrimmedUntil  types.String `tfsdk:"trimmed_until"`
+}
+
+func (r *UsageTrimResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_usage_trim"
+}
+
+func (r *UsageTrimResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	timeValidators := []validator.String{
+		stringvalidator.RegexMatches(usageTimeRegexp, "must be formatted as `YYYY-MM-DD` or `YYYY-MM-DD HH:MM:SS`"),
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Trims the usage log of the RGW. Usage entries are removed when the resource is created, any change of its attributes or `triggers` trims again. Destroying the resource does not restore any usage entries.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"uid": schema.StringAttribute{
+				MarkdownDescription: "The user to trim the usage log for. If not set, the usage log of all users is trimmed, which requires `remove_all`.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"start": schema.StringAttribute{
+				MarkdownDescription: "Trim usage entries starting at this time (`YYYY-MM-DD` or `YYYY-MM-DD HH:MM:SS`)",
+				Optional:            true,
+				Validators:          timeValidators,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"end": schema.StringAttribute{
+				MarkdownDescription: "Trim usage entries up to this time (`YYYY-MM-DD` or `YYYY-MM-DD HH:MM:SS`). Conflicts with `older_than_days`.",
+				Optional:            true,
+				Validators: append(timeValidators,
+					stringvalidator.ConflictsWith(path.MatchRoot("older_than_days")),
+				),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"older_than_days": schema.Int64Attribute{
+				MarkdownDescription: "Trim usage entries older than this number of days, relative to the time of apply. Conflicts with `end`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"remove_all": schema.BoolAttribute{
+				MarkdownDescription: "Required to trim the usage log of all users",
+				Optional:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				MarkdownDescription: "Arbitrary values which trim the usage log again when changed, e.g. a timestamp",
+				ElementType:         types.StringType,
+				Optional:            true,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"trimmed_until": schema.StringAttribute{
+				MarkdownDescription: "The end time used for the last trim operation",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *UsageTrimResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data UsageTrimResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.UID.IsNull() && !data.RemoveAll.IsUnknown() && !data.RemoveAll.ValueBool() {
+		resp.Diagnostics.AddAttributeError(path.Root("remove_all"), "remove_all required", "trimming the usage log of all users requires remove_all = true")
+	}
+}
+
+func (r *UsageTrimResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *UsageTrimResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *UsageTrimResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	usage := admin.Usage{
+		UserID: data.UID.ValueString(),
+		Start:  data.Start.ValueString(),
+		End:    data.End.ValueString(),
+	}
+	if !data.OlderThanDays.IsNull() {
+		usage.End = time.Now().UTC().AddDate(0, 0, -int(data.OlderThanDays.ValueInt64())).Format(usageTimeFormat)
+	}
+	if data.RemoveAll.ValueBool() {
+		removeAll := true
+		usage.RemoveAll = &removeAll
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("trim usage of user '%s' from '%s' until '%s'", usage.UserID, usage.Start, usage.End))
+
+	err := r.client.Admin.TrimUsage(ctx, usage)
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not trim usage", err)...)
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s:%s:%s", usage.UserID, usage.Start, usage.End))
+	data.TrimmedUntil = types.StringValue(usage.End)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UsageTrimResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Trimming is an action, there is nothing to read back
+}
+
+func (r *UsageTrimResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *UsageTrimResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// All attributes require replacement, there is nothing to update in place
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UsageTrimResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Trimmed usage entries can not be restored, just remove the resource from state
+}
@@ -0,0 +1,252 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &UserBulkDataSource{}
+
+func NewUserBulkDataSource() datasource.DataSource {
+	return &UserBulkDataSource{}
+}
+
+type UserBulkDataSource struct {
+	client *RgwClient
+}
+
+type UserBulkDataSourceModel struct {
+	UIDs          []types.String               `tfsdk:"uids"`
+	Concurrency   types.Int64                  `tfsdk:"concurrency"`
+	IgnoreMissing types.Bool                   `tfsdk:"ignore_missing"`
+	Users         map[string]UserBulkUserModel `tfsdk:"users"`
+}
+
+type UserBulkUserModel struct {
+	UID         types.String   `tfsdk:"uid"`
+	Tenant      types.String   `tfsdk:"tenant"`
+	DisplayName types.String   `tfsdk:"display_name"`
+	Email       types.String   `tfsdk:"email"`
+	Suspended   types.Bool     `tfsdk:"suspended"`
+	MaxBuckets  types.Int64    `tfsdk:"max_buckets"`
+	OpMask      types.String   `tfsdk:"op_mask"`
+	Caps        []UserCapModel `tfsdk:"caps"`
+	AccessKeys  []types.String `tfsdk:"access_keys"`
+	Principal   types.String   `tfsdk:"principal"`
+}
+
+func (d *UserBulkDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_user_bulk"
+}
+
+func (d *UserBulkDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Information about many RGW users at once. Users are fetched concurrently and returned in a single object, e.g. for policy audits across tenants. Secret keys are not exposed.",
+
+		Attributes: map[string]schema.Attribute{
+			"uids": schema.ListAttribute{
+				MarkdownDescription: "The UIDs of the users, `tenant$uid` for tenanted users",
+				ElementType:         types.StringType,
+				Required:            true,
+			},
+			"concurrency": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of concurrent api calls. Defaults to `10`.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, 100),
+				},
+			},
+			"ignore_missing": schema.BoolAttribute{
+				MarkdownDescription: "Omit users which do not exist from `users` instead of failing",
+				Optional:            true,
+			},
+			"users": schema.MapNestedAttribute{
+				MarkdownDescription: "The users keyed by the requested UID",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"uid": schema.StringAttribute{
+							MarkdownDescription: "The user ID (without tenant)",
+							Computed:            true,
+						},
+						"tenant": schema.StringAttribute{
+							MarkdownDescription: "The tenant of the user",
+							Computed:            true,
+						},
+						"display_name": schema.StringAttribute{
+							MarkdownDescription: "Display Name of user",
+							Computed:            true,
+						},
+						"email": schema.StringAttribute{
+							MarkdownDescription: "The email address associated with the user",
+							Computed:            true,
+						},
+						"suspended": schema.BoolAttribute{
+							MarkdownDescription: "Whether the user is suspended",
+							Computed:            true,
+						},
+						"max_buckets": schema.Int64Attribute{
+							MarkdownDescription: "The maximum number of buckets the user can own",
+							Computed:            true,
+						},
+						"op_mask": schema.StringAttribute{
+							MarkdownDescription: "The op-mask of the user",
+							Computed:            true,
+						},
+						"caps": schema.ListNestedAttribute{
+							MarkdownDescription: "The admin capabilities of the user",
+							Computed:            true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"type": schema.StringAttribute{
+										Computed: true,
+									},
+									"perm": schema.StringAttribute{
+										Computed: true,
+									},
+								},
+							},
+						},
+						"access_keys": schema.ListAttribute{
+							MarkdownDescription: "The S3 access keys of the user",
+							ElementType:         types.StringType,
+							Computed:            true,
+						},
+						"principal": schema.StringAttribute{
+							MarkdownDescription: "Principal to be used in policies",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *UserBulkDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *UserBulkDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data UserBulkDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	uids := make([]string, 0, len(data.UIDs))
+	for _, uid := range data.UIDs {
+		uids = append(uids, uid.ValueString())
+	}
+
+	concurrency := defaultConcurrency
+	if !data.Concurrency.IsNull() {
+		concurrency = int(data.Concurrency.ValueInt64())
+	}
+
+	users, errs := getUsers(ctx, d.client.Admin, uids, concurrency)
+	if data.IgnoreMissing.ValueBool() {
+		for uid, err := range errs {
+			if errors.Is(err, admin.ErrNoSuchUser) {
+				delete(errs, uid)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		resp.Diagnostics.AddError(fmt.Sprintf("could not get %d users", len(errs)), joinKeyErrors(errs))
+		return
+	}
+
+	data.Users = make(map[string]UserBulkUserModel, len(users))
+	for uid, user := range users {
+		data.Users[uid] = userBulkUserFromUser(user)
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// getUsers fetches the given users concurrently, returning the users and the errors by uid.
+func getUsers(ctx context.Context, api *admin.API, uids []string, workers int) (map[string]admin.User, map[string]error) {
+	var mu sync.Mutex
+	users := make(map[string]admin.User, len(uids))
+
+	errs := forEachConcurrent(ctx, uids, workers, func(ctx context.Context, uid string) error {
+		user, err := api.GetUser(ctx, admin.User{ID: uid})
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		users[uid] = user
+		mu.Unlock()
+		return nil
+	})
+
+	return users, errs
+}
+
+func userBulkUserFromUser(user admin.User) UserBulkUserModel {
+	var model UserBulkUserModel
+
+	// split tenant from user id
+	if tenant, uid, ok := strings.Cut(user.ID, "$"); ok {
+		model.Tenant = types.StringValue(tenant)
+		model.UID = types.StringValue(uid)
+	} else {
+		model.Tenant = types.StringValue("")
+		model.UID = types.StringValue(user.ID)
+	}
+
+	model.DisplayName = types.StringValue(user.DisplayName)
+	model.Email = types.StringValue(user.Email)
+	model.Suspended = types.BoolValue(user.Suspended != nil && *user.Suspended > 0)
+	model.MaxBuckets = types.Int64Null()
+	if user.MaxBuckets != nil {
+		model.MaxBuckets = types.Int64Value(int64(*user.MaxBuckets))
+	}
+	model.OpMask = types.StringValue(user.OpMask)
+
+	model.Caps = make([]UserCapModel, len(user.Caps))
+	for i, c := range user.Caps {
+		model.Caps[i].Type = types.StringValue(c.Type)
+		model.Caps[i].Perm = types.StringValue(c.Perm)
+	}
+
+	model.AccessKeys = make([]types.String, len(user.Keys))
+	for i, k := range user.Keys {
+		model.AccessKeys[i] = types.StringValue(k.AccessKey)
+	}
+
+	model.Principal = types.StringValue(principalARN(model.Tenant.ValueString(), model.UID.ValueString()))
+
+	return model
+}
@@ -0,0 +1,204 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.ResourceWithConfigure = &UserRateLimitResource{}
+var _ resource.ResourceWithImportState = &UserRateLimitResource{}
+
+func NewUserRateLimitResource() resource.Resource {
+	return &UserRateLimitResource{}
+}
+
+type UserRateLimitResource struct {
+	client *RgwClient
+}
+
+type UserRateLimitResourceModel struct {
+	Id            types.String `tfsdk:"id"`
+	UID           types.String `tfsdk:"uid"`
+	Tenant        types.String `tfsdk:"tenant"`
+	Enabled       types.Bool   `tfsdk:"enabled"`
+	MaxReadOps    types.Int64  `tfsdk:"max_read_ops"`
+	MaxWriteOps   types.Int64  `tfsdk:"max_write_ops"`
+	MaxReadBytes  types.Int64  `tfsdk:"max_read_bytes"`
+	MaxWriteBytes types.Int64  `tfsdk:"max_write_bytes"`
+}
+
+// uid returns the uid qualified with the tenant.
+func (data *UserRateLimitResourceModel) uid() string {
+	return tenantedUID(data.Tenant.ValueString(), data.UID.ValueString())
+}
+
+func (r *UserRateLimitResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_user_rate_limit"
+}
+
+func (r *UserRateLimitResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	attributes := rateLimitAttributes()
+	attributes["id"] = schema.StringAttribute{
+		Computed: true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.UseStateForUnknown(),
+		},
+	}
+	attributes["uid"] = schema.StringAttribute{
+		MarkdownDescription: "The UID of the user to set the rate limit for.",
+		Required:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+	attributes["tenant"] = schema.StringAttribute{
+		MarkdownDescription: "The tenant of the user. If set, `uid` is qualified as `tenant$uid` for all api calls.",
+		Optional:            true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "This resource can be used to set the rate limit of a rgw user. Limits modified out-of-band, e.g. with `radosgw-admin ratelimit set`, are detected on refresh and planned back to the configuration. Upon deletion, the rate limit is disabled and its limits are reset. Existing rate limits can be imported by the uid, as `tenant$uid` for users of a tenant. Requires Ceph >= quincy.",
+		Attributes:          attributes,
+	}
+}
+
+func (r *UserRateLimitResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = client
+}
+
+func (r *UserRateLimitResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	resp.Diagnostics.Append(requireCephRelease(r.client, 17, "rgw_user_rate_limit")...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *UserRateLimitResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	limit := rateLimitFromSchema(data.Enabled, data.MaxReadOps, data.MaxWriteOps, data.MaxReadBytes, data.MaxWriteBytes)
+	if err := setRateLimit(ctx, r.client, "user", data.uid(), "", limit); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create user rate limit", err)...)
+		return
+	}
+	data.Id = types.StringValue(data.uid())
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UserRateLimitResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Read Terraform prior state data into the model
+	var data *UserRateLimitResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	limit, err := getRateLimit(ctx, r.client, "user", data.uid(), "")
+	if err != nil {
+		if errors.Is(err, admin.ErrNoSuchUser) {
+			// Remove rate limit from state
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get user rate limit", err)...)
+		return
+	}
+
+	// always take the limits of the gateway, so out-of-band changes show up as drift
+	rateLimitToSchema(limit, &data.Enabled, &data.MaxReadOps, &data.MaxWriteOps, &data.MaxReadBytes, &data.MaxWriteBytes)
+	data.Id = types.StringValue(data.uid())
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UserRateLimitResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform plan data into the model
+	var data *UserRateLimitResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	limit := rateLimitFromSchema(data.Enabled, data.MaxReadOps, data.MaxWriteOps, data.MaxReadBytes, data.MaxWriteBytes)
+	if err := setRateLimit(ctx, r.client, "user", data.uid(), "", limit); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify user rate limit", err)...)
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *UserRateLimitResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Read Terraform prior state data into the model
+	var data *UserRateLimitResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// nothing to disable if the user is already gone
+	err := setRateLimit(ctx, r.client, "user", data.uid(), "", rgwRateLimit{})
+	if err != nil && !errors.Is(err, admin.ErrNoSuchUser) {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete user rate limit", err)...)
+		return
+	}
+}
+
+func (r *UserRateLimitResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// the rate limit is imported by the uid, optionally qualified as "tenant$uid"
+	uid := req.ID
+	if tenant, name, ok := strings.Cut(req.ID, "$"); ok {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("tenant"), tenant)...)
+		uid = name
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("uid"), uid)...)
+}
@@ -2,18 +2,26 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/ceph/go-ceph/rgw/admin"
+	"github.com/hashicorp/terraform-plugin-framework-validators/boolvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
@@ -23,8 +31,15 @@ import (
 
 const accessKeyBytes = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
+// uidSuffixBytes and uidSuffixLength describe the random suffix of generated user IDs.
+const (
+	uidSuffixBytes  = "0123456789abcdefghijklmnopqrstuvwxyz"
+	uidSuffixLength = 8
+)
+
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.ResourceWithConfigure = &UserResource{}
+var _ resource.ResourceWithModifyPlan = &UserResource{}
 
 func NewUserResource() resource.Resource {
 	return &UserResource{}
@@ -37,6 +52,7 @@ type UserResource struct {
 type UserResourceModel struct {
 	Id                     types.String   `tfsdk:"id"`
 	Username               types.String   `tfsdk:"username"`
+	UsernamePrefix         types.String   `tfsdk:"username_prefix"`
 	DisplayName            types.String   `tfsdk:"display_name"`
 	Email                  types.String   `tfsdk:"email"`
 	GenerateS3Credentials  types.Bool     `tfsdk:"generate_s3_credentials"`
@@ -50,6 +66,10 @@ type UserResourceModel struct {
 	SecretKey              types.String   `tfsdk:"secret_key"`
 	PurgeDataOnDelete      types.Bool     `tfsdk:"purge_data_on_delete"`
 	Principal              types.String   `tfsdk:"principal"`
+	CheckEmailUnique       types.Bool     `tfsdk:"check_email_unique"`
+	AccessKeyIDs           types.List     `tfsdk:"access_key_ids"`
+	AccountID              types.String   `tfsdk:"account_id"`
+	AccountRoot            types.Bool     `tfsdk:"account_root"`
 }
 
 type UserCapModel struct {
@@ -74,10 +94,23 @@ func (r *UserResource) Schema(ctx context.Context, req resource.SchemaRequest, r
 				},
 			},
 			"username": schema.StringAttribute{
-				MarkdownDescription: "The user ID to be created (without tenant).",
-				Required:            true,
+				MarkdownDescription: "The user ID to be created (without tenant). Exactly one of `username` and `username_prefix` must be set, the ID is generated from the prefix otherwise.",
+				Optional:            true,
+				Computed:            true,
 				Validators: []validator.String{
 					stringvalidator.NoneOf("$"),
+					stringvalidator.ExactlyOneOf(path.MatchRoot("username_prefix")),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"username_prefix": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("Generate the user ID from this prefix and a random suffix of %d lowercase letters and digits, e.g. for ephemeral users of CI jobs. The generated ID is available as `username`.", uidSuffixLength),
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(regexp.MustCompile(`^[^$]*$`), "must not contain `$`"),
 				},
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.RequiresReplace(),
@@ -91,6 +124,10 @@ func (r *UserResource) Schema(ctx context.Context, req resource.SchemaRequest, r
 				MarkdownDescription: "The email address associated with the user.",
 				Optional:            true,
 			},
+			"check_email_unique": schema.BoolAttribute{
+				MarkdownDescription: "Check at plan time whether the email address is already used by another user, which RGW rejects with an unspecific error. The check fetches all users and is slow on gateways with many users. Defaults to `false`.",
+				Optional:            true,
+			},
 			"generate_s3_credentials": schema.BoolAttribute{
 				Description:         "Specify whether to generate S3 Credentials for the user",
 				MarkdownDescription: "Specify whether to generate S3 Credentials for the user. Set to false to generate swift keys via rgw_subuser.",
@@ -98,7 +135,7 @@ func (r *UserResource) Schema(ctx context.Context, req resource.SchemaRequest, r
 			},
 			"exclusive_s3_credentials": schema.BoolAttribute{
 				Description:         "Specify whether other s3 credentials for this user not managed by this ressource should be deleted.",
-				MarkdownDescription: "Specify how to deal with s3 credentials for this user not managed by this resource. Set to `true` to delete all other s3 credentials. Set to `false` to ignore other credentials.",
+				MarkdownDescription: "Specify how to deal with s3 credentials for this user not managed by this resource. Set to `true` to delete all other s3 credentials. Set to `false` to keep other credentials, they are still reported with a warning on every refresh, as is a managed key missing on the gateway, so leaked or forgotten keys are noticed.",
 				Optional:            true,
 			},
 			"caps": schema.SetNestedAttribute{
@@ -159,7 +196,7 @@ func (r *UserResource) Schema(ctx context.Context, req resource.SchemaRequest, r
 			"secret_key": schema.StringAttribute{
 				MarkdownDescription: "The generated secret key",
 				Computed:            true,
-				//Sensitive:           true,
+				Sensitive:           true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
 					stringPrivateUnknownModifier{"secret_key"},
@@ -173,6 +210,35 @@ func (r *UserResource) Schema(ctx context.Context, req resource.SchemaRequest, r
 				MarkdownDescription: "Computed principal to be used in policies",
 				Computed:            true,
 			},
+			"account_id": schema.StringAttribute{
+				MarkdownDescription: "The ID of the RGW account the user belongs to. Existing users can be moved into an account, changing or removing the account replaces the user. Requires Ceph >= squid.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIf(func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+						resp.RequiresReplace = !req.StateValue.IsNull()
+					}, "Changing the account of a user requires replacement", "Changing the account of a user requires replacement"),
+				},
+			},
+			"account_root": schema.BoolAttribute{
+				MarkdownDescription: "Whether the user is the root user of its account, which has full access to all resources of the account. Requires `account_id`.",
+				Optional:            true,
+				Computed:            true,
+				Validators: []validator.Bool{
+					boolvalidator.AlsoRequires(path.MatchRoot("account_id")),
+				},
+				PlanModifiers: []planmodifier.Bool{
+					boolDefaultModifier{false},
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"access_key_ids": schema.ListAttribute{
+				MarkdownDescription: "All S3 access keys of the user, including keys created outside of Terraform. Refreshed on read, so foreign keys show up as drift even without `exclusive_s3_credentials`.",
+				ElementType:         types.StringType,
+				Computed:            true,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
 		},
 	}
 }
@@ -197,7 +263,110 @@ func (r *UserResource) Configure(ctx context.Context, req resource.ConfigureRequ
 	r.client = client
 }
 
+func (r *UserResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to do on destroy
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	// accounts are supported since squid
+	var accountID types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("account_id"), &accountID)...)
+	if !accountID.IsNull() {
+		resp.Diagnostics.Append(requireCephRelease(r.client, 19, "account_id on rgw_user")...)
+	}
+
+	// the access keys only change if a key is generated or foreign keys are deleted
+	if !req.State.Raw.IsNull() {
+		var accessKey, secretKey types.String
+		var exclusive, stateExclusive, generate, stateGenerate types.Bool
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("access_key"), &accessKey)...)
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("secret_key"), &secretKey)...)
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("exclusive_s3_credentials"), &exclusive)...)
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("generate_s3_credentials"), &generate)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("exclusive_s3_credentials"), &stateExclusive)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("generate_s3_credentials"), &stateGenerate)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if accessKey.IsUnknown() || secretKey.IsUnknown() || !exclusive.Equal(stateExclusive) || !generate.Equal(stateGenerate) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("access_key_ids"), types.ListUnknown(types.StringType))...)
+		}
+	}
+
+	var check types.Bool
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("check_email_unique"), &check)...)
+	var email types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("email"), &email)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !check.ValueBool() || email.IsUnknown() || email.ValueString() == "" {
+		return
+	}
+
+	// only check new users and changed emails
+	var id types.String
+	if !req.State.Raw.IsNull() {
+		var stateEmail types.String
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("email"), &stateEmail)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("id"), &id)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if strings.EqualFold(stateEmail.ValueString(), email.ValueString()) {
+			return
+		}
+	}
+
+	uid, err := r.findUserByEmail(ctx, email.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeWarning(path.Root("email"), "could not check whether the email is unique", err.Error())
+		return
+	}
+	if uid != "" && uid != id.ValueString() {
+		resp.Diagnostics.AddAttributeError(path.Root("email"), "email already in use", fmt.Sprintf("email '%s' is already used by user '%s'", email.ValueString(), uid))
+	}
+}
+
+// findUserByEmail returns the id of the user with the given email, an empty string if there is none.
+// The admin api can not look up users by email, so all users are fetched.
+func (r *UserResource) findUserByEmail(ctx context.Context, email string) (string, error) {
+	uids, err := r.client.Admin.GetUsers(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not list users: %w", err)
+	}
+	if uids == nil {
+		return "", nil
+	}
+
+	users, errs := getUsers(ctx, r.client.Admin, *uids, defaultConcurrency)
+	for uid, user := range users {
+		if strings.EqualFold(user.Email, email) {
+			return uid, nil
+		}
+	}
+	if len(errs) > 0 {
+		return "", fmt.Errorf("could not get %d users: %s", len(errs), joinKeyErrors(errs))
+	}
+	return "", nil
+}
+
+// generateUID returns a user ID with the prefix and a random suffix.
+func generateUID(prefix string) string {
+	suffix := make([]byte, uidSuffixLength)
+	for i := range suffix {
+		suffix[i] = uidSuffixBytes[rand.Intn(len(uidSuffixBytes))]
+	}
+	return prefix + string(suffix)
+}
+
 func (r *UserResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *UserResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -205,6 +374,10 @@ func (r *UserResource) Create(ctx context.Context, req resource.CreateRequest, r
 		return
 	}
 
+	if data.Username.IsUnknown() {
+		data.Username = types.StringValue(generateUID(data.UsernamePrefix.ValueString()))
+	}
+
 	// Create API user object
 	rgwUser := admin.User{
 		DisplayName: data.DisplayName.ValueString(),
@@ -216,6 +389,7 @@ func (r *UserResource) Create(ctx context.Context, req resource.CreateRequest, r
 	} else {
 		rgwUser.ID = fmt.Sprintf("%s$%s", data.Tenant.ValueString(), data.Username.ValueString())
 	}
+	defer r.client.lockUser(rgwUser.ID)()
 	generateKey := false
 	if data.GenerateS3Credentials.ValueBool() || data.GenerateS3Credentials.IsNull() {
 		generateKey = true
@@ -235,7 +409,22 @@ func (r *UserResource) Create(ctx context.Context, req resource.CreateRequest, r
 	// create user
 	createdUser, err := r.client.Admin.CreateUser(ctx, rgwUser)
 	if err != nil {
-		resp.Diagnostics.AddError("could not create user", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not create user", err)...)
+		return
+	}
+
+	// go-ceph does not support accounts, the user is moved into its account right after creation
+	if !data.AccountID.IsNull() {
+		if err := setUserAccount(ctx, r.client.Admin, createdUser.ID, data.AccountID.ValueString(), data.AccountRoot.ValueBool()); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not add user to account", err)...)
+			return
+		}
+	}
+	data.AccountRoot = types.BoolValue(data.AccountRoot.ValueBool())
+
+	// seed the user with the default quotas of the provider
+	if err := setDefaultQuotas(ctx, r.client, createdUser.ID); err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not set default quota", err)...)
 		return
 	}
 
@@ -248,14 +437,15 @@ func (r *UserResource) Create(ctx context.Context, req resource.CreateRequest, r
 		userCap := strings.Join(userCapSlice, ";")
 		_, err := r.client.Admin.AddUserCap(ctx, createdUser.ID, userCap)
 		if err != nil {
-			resp.Diagnostics.AddError("could not add user cap", err.Error())
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not add user cap", err)...)
 			return
 		}
 	}
 
 	// set resource id
 	data.Id = types.StringValue(createdUser.ID)
-	data.Principal = types.StringValue(fmt.Sprintf("arn:aws:iam::%s:user/%s", data.Tenant.ValueString(), data.Username.ValueString()))
+	resp.Diagnostics.Append(data.setAccessKeyIDs(ctx, createdUser)...)
+	data.Principal = types.StringValue(principalARN(data.Tenant.ValueString(), data.Username.ValueString()))
 
 	// set access and secret key
 	if generateKey {
@@ -288,15 +478,15 @@ func (r *UserResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		ID: data.Id.ValueString(),
 	}
 
-	// get user
-	user, err := r.client.Admin.GetUser(ctx, reqUser)
+	// get user including its account membership with a single request
+	user, account, err := getUserInfo(ctx, r.client.Admin, reqUser.ID)
 	if err != nil {
 		if errors.Is(err, admin.ErrNoSuchUser) {
 			// Remove user from state
 			resp.State.RemoveResource(ctx)
 			return
 		}
-		resp.Diagnostics.AddError("could not get user", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get user", err)...)
 		return
 	}
 
@@ -351,9 +541,18 @@ func (r *UserResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		}
 	}
 
+	// update account membership
+	if !data.AccountID.IsNull() || r.client.CephRelease >= 19 {
+		if account.AccountID != "" || !data.AccountID.IsNull() {
+			data.AccountID = types.StringValue(account.AccountID)
+		}
+		data.AccountRoot = types.BoolValue(account.root())
+	}
+
 	// update credentials
-	tflog.Info(ctx, fmt.Sprintf("In Read: Keys returned from API %v", user.Keys))
-	tflog.Info(ctx, fmt.Sprintf("In Read: State access_key %s, secret_key %s", data.AccessKey.ValueString(), data.SecretKey.ValueString()))
+	tflog.Info(ctx, fmt.Sprintf("In Read: Access keys returned from API %v", userAccessKeys(user)))
+	resp.Diagnostics.Append(data.setAccessKeyIDs(ctx, user)...)
+	tflog.Info(ctx, fmt.Sprintf("In Read: State access_key %s", data.AccessKey.ValueString()))
 	if data.GenerateS3Credentials.ValueBool() || data.GenerateS3Credentials.IsNull() {
 		found := false
 		if data.AccessKey.IsNull() || data.AccessKey.IsUnknown() {
@@ -375,6 +574,24 @@ func (r *UserResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		if len(user.Keys) > 1 || (len(user.Keys) == 1 && !found) {
 			data.ExclusiveS3Credentials = types.BoolValue(false)
 		}
+
+		// keys differing from the managed key might be leaked or forgotten credentials, so
+		// they are reported on every refresh even if they are not deleted
+		if !data.AccessKey.IsNull() && !data.AccessKey.IsUnknown() {
+			extra, missing := unmanagedAccessKeys(user, data.AccessKey.ValueString())
+			if len(extra) > 0 {
+				resp.Diagnostics.AddWarning(
+					"s3 keys not managed by rgw_user",
+					fmt.Sprintf("user %s has %d s3 keys which are not managed by this resource: %s. Remove keys which are not used anymore, or set exclusive_s3_credentials to delete them on apply.", user.ID, len(extra), strings.Join(extra, ", ")),
+				)
+			}
+			if len(missing) > 0 {
+				resp.Diagnostics.AddWarning(
+					"s3 key of rgw_user missing",
+					fmt.Sprintf("the s3 key %s managed by this resource was removed from user %s.", strings.Join(missing, ", "), user.ID),
+				)
+			}
+		}
 	} else {
 		resp.Diagnostics.Append(resp.Private.SetKey(ctx, "mark_unknown_access_key", []byte("0"))...)
 		resp.Diagnostics.Append(resp.Private.SetKey(ctx, "mark_unknown_secret_key", []byte("0"))...)
@@ -387,6 +604,11 @@ func (r *UserResource) Read(ctx context.Context, req resource.ReadRequest, resp
 }
 
 func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform plan data into the model
 	var data *UserResourceModel
 	var dataState *UserResourceModel
@@ -400,6 +622,8 @@ func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		return
 	}
 
+	defer r.client.lockUser(data.Id.ValueString())()
+
 	// instantiate api request user struct
 	update := admin.User{
 		ID:          data.Id.ValueString(),
@@ -426,10 +650,18 @@ func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, r
 	// modify user
 	user, err := r.client.Admin.ModifyUser(ctx, update)
 	if err != nil {
-		resp.Diagnostics.AddError("could not modify user", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify user", err)...)
 		return
 	}
 
+	// update account membership
+	if !data.AccountID.IsNull() && (!data.AccountID.Equal(dataState.AccountID) || !data.AccountRoot.Equal(dataState.AccountRoot)) {
+		if err := setUserAccount(ctx, r.client.Admin, data.Id.ValueString(), data.AccountID.ValueString(), data.AccountRoot.ValueBool()); err != nil {
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not modify user account", err)...)
+			return
+		}
+	}
+
 	// update caps
 	if len(dataState.Caps) > 0 {
 		userCapSlice := make([]string, len(dataState.Caps))
@@ -439,7 +671,7 @@ func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		userCap := strings.Join(userCapSlice, ";")
 		_, err := r.client.Admin.RemoveUserCap(ctx, data.Id.ValueString(), userCap)
 		if err != nil {
-			resp.Diagnostics.AddError("could not remove user cap", err.Error())
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not remove user cap", err)...)
 			return
 		}
 	}
@@ -454,13 +686,13 @@ func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		userCap := strings.Join(userCapSlice, ";")
 		_, err := r.client.Admin.AddUserCap(ctx, data.Id.ValueString(), userCap)
 		if err != nil {
-			resp.Diagnostics.AddError("could not add user cap", err.Error())
+			resp.Diagnostics.Append(errorDiagnostics(r.client, "could not add user cap", err)...)
 			return
 		}
 	}
 
 	// manage s3 keys
-	tflog.Info(ctx, fmt.Sprintf("In Update: Keys returned from API %v", user.Keys))
+	tflog.Info(ctx, fmt.Sprintf("In Update: Access keys returned from API %v", userAccessKeys(user)))
 	if data.GenerateS3Credentials.ValueBool() || data.GenerateS3Credentials.IsNull() {
 		tflog.Info(ctx, fmt.Sprintf("Access Key unknown: %t, Secret Key unknown: %t", data.AccessKey.IsUnknown(), data.SecretKey.IsUnknown()))
 		if data.SecretKey.IsUnknown() {
@@ -472,7 +704,7 @@ func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, r
 					} else if data.ExclusiveS3Credentials.ValueBool() || data.ExclusiveS3Credentials.IsNull() {
 						k.UID = user.ID
 						if err := r.client.Admin.RemoveKey(ctx, k); err != nil {
-							resp.Diagnostics.AddError(fmt.Sprintf("could not remove access key '%s'", k.AccessKey), err.Error())
+							resp.Diagnostics.Append(errorDiagnostics(r.client, fmt.Sprintf("could not remove access key '%s'", k.AccessKey), err)...)
 						}
 					}
 				}
@@ -496,7 +728,7 @@ func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, r
 					AccessKey:   data.AccessKey.ValueString(),
 				})
 				if err != nil {
-					resp.Diagnostics.AddError("could not generate s3 credentials", err.Error())
+					resp.Diagnostics.Append(errorDiagnostics(r.client, "could not generate s3 credentials", err)...)
 					return
 				}
 
@@ -523,7 +755,7 @@ func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, r
 				if k.AccessKey != data.AccessKey.ValueString() {
 					k.UID = user.ID
 					if err := r.client.Admin.RemoveKey(ctx, k); err != nil {
-						resp.Diagnostics.AddError(fmt.Sprintf("could not remove access key '%s'", k.AccessKey), err.Error())
+						resp.Diagnostics.Append(errorDiagnostics(r.client, fmt.Sprintf("could not remove access key '%s'", k.AccessKey), err)...)
 					}
 				}
 			}
@@ -534,7 +766,7 @@ func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, r
 			for _, k := range user.Keys {
 				k.UID = user.ID
 				if err := r.client.Admin.RemoveKey(ctx, k); err != nil {
-					resp.Diagnostics.AddError(fmt.Sprintf("could not remove access key '%s'", k.AccessKey), err.Error())
+					resp.Diagnostics.Append(errorDiagnostics(r.client, fmt.Sprintf("could not remove access key '%s'", k.AccessKey), err)...)
 				}
 			}
 		}
@@ -543,13 +775,26 @@ func (r *UserResource) Update(ctx context.Context, req resource.UpdateRequest, r
 	}
 
 	data.Id = types.StringValue(user.ID)
-	data.Principal = types.StringValue(fmt.Sprintf("arn:aws:iam::%s:user/%s", data.Tenant.ValueString(), data.Username.ValueString()))
+	data.Principal = types.StringValue(principalARN(data.Tenant.ValueString(), data.Username.ValueString()))
+
+	// refresh the key inventory after keys were added or removed
+	user, err = r.client.Admin.GetUser(ctx, admin.User{ID: user.ID})
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get user", err)...)
+		return
+	}
+	resp.Diagnostics.Append(data.setAccessKeyIDs(ctx, user)...)
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *UserResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(requireWritable(r.client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Read Terraform prior state data into the model
 	var data *UserResourceModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -557,10 +802,12 @@ func (r *UserResource) Delete(ctx context.Context, req resource.DeleteRequest, r
 		return
 	}
 
+	defer r.client.lockUser(data.Id.ValueString())()
+
 	// get user's buckets
 	buckets, err := r.client.Admin.ListUsersBuckets(ctx, data.Id.ValueString())
 	if err != nil {
-		resp.Diagnostics.AddError("could not get user's buckets", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not get user's buckets", err)...)
 		return
 	}
 
@@ -579,7 +826,7 @@ func (r *UserResource) Delete(ctx context.Context, req resource.DeleteRequest, r
 		PurgeData: &purgeData,
 	})
 	if err != nil && !errors.Is(err, admin.ErrNoSuchUser) {
-		resp.Diagnostics.AddError("could not delete user", err.Error())
+		resp.Diagnostics.Append(errorDiagnostics(r.client, "could not delete user", err)...)
 		return
 	}
 }
@@ -614,3 +861,85 @@ func (m stringPrivateUnknownModifier) PlanModifyString(ctx context.Context, req
 		}
 	}
 }
+
+// setAccessKeyIDs sets the sorted access keys of the user.
+func (data *UserResourceModel) setAccessKeyIDs(ctx context.Context, user admin.User) diag.Diagnostics {
+	keys := userAccessKeys(user)
+	sort.Strings(keys)
+
+	var diags diag.Diagnostics
+	data.AccessKeyIDs, diags = types.ListValueFrom(ctx, types.StringType, keys)
+	return diags
+}
+
+// userAccessKeys returns the s3 access keys of a user without their secrets, e.g. for logging.
+func userAccessKeys(user admin.User) []string {
+	keys := make([]string, len(user.Keys))
+	for i, k := range user.Keys {
+		keys[i] = k.AccessKey
+	}
+	return keys
+}
+
+// unmanagedAccessKeys compares the s3 access keys of the user with the managed access key.
+// It returns the keys the user has in addition to the managed key and the managed key if it
+// is missing. Keys of subusers, listed as "uid:subuser", are not reported.
+func unmanagedAccessKeys(user admin.User, managed string) (extra, missing []string) {
+	found := false
+	for _, k := range user.Keys {
+		if k.AccessKey == managed {
+			found = true
+			continue
+		}
+		if k.User == user.ID {
+			extra = append(extra, k.AccessKey)
+		}
+	}
+	sort.Strings(extra)
+	if !found {
+		missing = []string{managed}
+	}
+	return extra, missing
+}
+
+// rgwUserAccount describes the account membership of a user.
+type rgwUserAccount struct {
+	AccountID string `json:"account_id"`
+	Type      string `json:"type"`
+}
+
+// root reports whether the user is the root user of its account.
+func (a rgwUserAccount) root() bool {
+	return a.AccountID != "" && a.Type == "root"
+}
+
+// getUserInfo fetches a user along with its account membership, which go-ceph does not
+// decode, from a single response.
+func getUserInfo(ctx context.Context, api *admin.API, uid string) (admin.User, rgwUserAccount, error) {
+	body, err := adminCall(ctx, api, http.MethodGet, "/user", url.Values{
+		"uid": {uid},
+	})
+	if err != nil {
+		return admin.User{}, rgwUserAccount{}, err
+	}
+
+	var user admin.User
+	if err := json.Unmarshal(body, &user); err != nil {
+		return admin.User{}, rgwUserAccount{}, fmt.Errorf("could not decode user: %w", err)
+	}
+	var account rgwUserAccount
+	if err := json.Unmarshal(body, &account); err != nil {
+		return admin.User{}, rgwUserAccount{}, fmt.Errorf("could not decode user: %w", err)
+	}
+	return user, account, nil
+}
+
+// setUserAccount moves a user into an account and sets whether it is the account root user.
+func setUserAccount(ctx context.Context, api *admin.API, uid, accountID string, root bool) error {
+	_, err := adminCall(ctx, api, http.MethodPost, "/user", url.Values{
+		"uid":          {uid},
+		"account-id":   {accountID},
+		"account-root": {fmt.Sprintf("%t", root)},
+	})
+	return err
+}
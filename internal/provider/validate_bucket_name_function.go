@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &ValidateBucketNameFunction{}
+
+func NewValidateBucketNameFunction() function.Function {
+	return &ValidateBucketNameFunction{}
+}
+
+type ValidateBucketNameFunction struct{}
+
+func (f *ValidateBucketNameFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "validate_bucket_name"
+}
+
+func (f *ValidateBucketNameFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Validate the name of a RGW bucket",
+		MarkdownDescription: "Checks a bucket name against the naming rules RGW enforces unless `rgw_relaxed_s3_bucket_names` is enabled: 3 to 63 characters, only lowercase letters, digits, dots and hyphens, starting and ending with a letter or digit, no adjacent dots and not formatted as an IP address. Returns `true` for a valid name and fails with the violated rule otherwise, so it can be used directly as `condition` of `validation` and `precondition` blocks, or wrapped in `can()` to get `false` instead.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "name",
+				MarkdownDescription: "The bucket name, without tenant",
+			},
+		},
+		Return: function.BoolReturn{},
+	}
+}
+
+func (f *ValidateBucketNameFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var name string
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &name))
+	if resp.Error != nil {
+		return
+	}
+
+	if err := validateBucketName(name); err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, true))
+}
+
+// validateBucketName checks a bucket name against the strict s3 naming rules of RGW.
+func validateBucketName(name string) error {
+	if len(name) < 3 || len(name) > 63 {
+		return fmt.Errorf("bucket name %q must be between 3 and 63 characters long", name)
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-') {
+			return fmt.Errorf("bucket name %q must only contain lowercase letters, digits, dots and hyphens, found %q", name, c)
+		}
+	}
+	if !isLowerAlnum(name[0]) || !isLowerAlnum(name[len(name)-1]) {
+		return fmt.Errorf("bucket name %q must start and end with a lowercase letter or digit", name)
+	}
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("bucket name %q must not contain adjacent dots", name)
+	}
+	if net.ParseIP(name) != nil {
+		return fmt.Errorf("bucket name %q must not be formatted as an IP address", name)
+	}
+	return nil
+}
+
+// isLowerAlnum reports whether c is a lowercase letter or digit.
+func isLowerAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// cephReleases maps the release names of Ceph to their major version.
+var cephReleases = map[string]int{
+	"luminous": 12,
+	"mimic":    13,
+	"nautilus": 14,
+	"octopus":  15,
+	"pacific":  16,
+	"quincy":   17,
+	"reef":     18,
+	"squid":    19,
+	"tentacle": 20,
+}
+
+var cephVersionRegexp = regexp.MustCompile(`\b(\d{2})\.\d+\.\d+\b`)
+
+// parseCephRelease returns the major version of Ceph from a Server header
+// like "Ceph Object Gateway (18.2.1 reef)", 0 if it contains no version.
+func parseCephRelease(server string) int {
+	if m := cephVersionRegexp.FindStringSubmatch(server); m != nil {
+		major, _ := strconv.Atoi(m[1])
+		return major
+	}
+	for _, field := range strings.FieldsFunc(strings.ToLower(server), func(r rune) bool {
+		return r == ' ' || r == '(' || r == ')' || r == '/'
+	}) {
+		if major, ok := cephReleases[field]; ok {
+			return major
+		}
+	}
+	return 0
+}
+
+// cephReleaseName returns the release name of a major version of Ceph.
+func cephReleaseName(major int) string {
+	for name, m := range cephReleases {
+		if m == major {
+			return fmt.Sprintf("%s (%d)", name, major)
+		}
+	}
+	return strconv.Itoa(major)
+}
+
+// detectCephRelease returns the major version of Ceph the gateway runs, 0 if it can not be
+// detected. The gateway reports its version in the Server header of every response.
+func detectCephRelease(ctx context.Context, client *http.Client, endpoint string) int {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		tflog.Debug(ctx, fmt.Sprintf("could not detect ceph version: %s", err.Error()))
+		return 0
+	}
+	resp.Body.Close()
+
+	release := parseCephRelease(resp.Header.Get("Server"))
+	tflog.Debug(ctx, fmt.Sprintf("detected ceph release %d from server header '%s'", release, resp.Header.Get("Server")))
+	return release
+}
+
+// requireCephRelease fails if the gateway is known to run a Ceph release older than release.
+// An unknown release passes, the gateway rejects unsupported requests itself then.
+func requireCephRelease(client *RgwClient, release int, feature string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if client == nil || client.CephRelease == 0 || client.CephRelease >= release {
+		return diags
+	}
+	diags.AddError(
+		"unsupported ceph version",
+		fmt.Sprintf("%s requires Ceph >= %s, the gateway runs %s", feature, cephReleaseName(release), cephReleaseName(client.CephRelease)),
+	)
+	return diags
+}
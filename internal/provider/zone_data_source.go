@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSourceWithConfigure = &ZoneDataSource{}
+
+func NewZoneDataSource() datasource.DataSource {
+	return &ZoneDataSource{}
+}
+
+type ZoneDataSource struct {
+	client *RgwClient
+}
+
+type ZoneDataSourceModel struct {
+	ID             types.String             `tfsdk:"id"`
+	Name           types.String             `tfsdk:"name"`
+	PlacementPools []ZonePlacementPoolModel `tfsdk:"placement_pools"`
+}
+
+type ZonePlacementPoolModel struct {
+	Name           types.String            `tfsdk:"name"`
+	IndexPool      types.String            `tfsdk:"index_pool"`
+	DataExtraPool  types.String            `tfsdk:"data_extra_pool"`
+	StorageClasses []ZoneStorageClassModel `tfsdk:"storage_classes"`
+}
+
+type ZoneStorageClassModel struct {
+	Name            types.String `tfsdk:"name"`
+	DataPool        types.String `tfsdk:"data_pool"`
+	CompressionType types.String `tfsdk:"compression_type"`
+}
+
+func (d *ZoneDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_zone"
+}
+
+func (d *ZoneDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Placement pools of the zone the gateway is serving. The pools and compression can not be managed by this provider: the zone configuration is part of the period, so changes have to be applied with `radosgw-admin zone placement modify` and committed with `radosgw-admin period update --commit`, which the Admin Ops API does not offer. To review storage-tier changes in PRs anyway, keep the expected pools in the configuration and compare them with this data source in `check` blocks or postconditions, then apply reviewed changes with `radosgw-admin` and let the check confirm them.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "ID of the zone",
+				Computed:            true,
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name of the zone",
+				Computed:            true,
+			},
+			"placement_pools": schema.ListNestedAttribute{
+				MarkdownDescription: "The pools of each placement target, sorted by name",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Name of the placement target",
+							Computed:            true,
+						},
+						"index_pool": schema.StringAttribute{
+							MarkdownDescription: "Pool of the bucket indexes",
+							Computed:            true,
+						},
+						"data_extra_pool": schema.StringAttribute{
+							MarkdownDescription: "Pool of incomplete multipart uploads",
+							Computed:            true,
+						},
+						"storage_classes": schema.ListNestedAttribute{
+							MarkdownDescription: "The storage classes of the placement target, sorted by name",
+							Computed:            true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"name": schema.StringAttribute{
+										MarkdownDescription: "Name of the storage class, e.g. `STANDARD`",
+										Computed:            true,
+									},
+									"data_pool": schema.StringAttribute{
+										MarkdownDescription: "Pool of the object data",
+										Computed:            true,
+									},
+									"compression_type": schema.StringAttribute{
+										MarkdownDescription: "Compression of the object data, e.g. `zlib`. Empty if objects are not compressed.",
+										Computed:            true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *ZoneDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*RgwClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *RgwClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = client
+}
+
+func (d *ZoneDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Read Terraform configuration data into the model
+	var data ZoneDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	zone, err := getZone(ctx, d.client.Admin)
+	if err != nil {
+		resp.Diagnostics.Append(errorDiagnostics(d.client, "could not get zone", err)...)
+		return
+	}
+
+	data.ID = types.StringValue(zone.ID)
+	data.Name = types.StringValue(zone.Name)
+
+	data.PlacementPools = make([]ZonePlacementPoolModel, 0, len(zone.PlacementPools))
+	for _, pp := range zone.PlacementPools {
+		storageClasses := make([]ZoneStorageClassModel, 0, len(pp.Val.StorageClasses))
+		for name, sc := range pp.Val.StorageClasses {
+			storageClasses = append(storageClasses, ZoneStorageClassModel{
+				Name:            types.StringValue(name),
+				DataPool:        types.StringValue(sc.DataPool),
+				CompressionType: types.StringValue(sc.CompressionType),
+			})
+		}
+		sort.Slice(storageClasses, func(i, j int) bool {
+			return storageClasses[i].Name.ValueString() < storageClasses[j].Name.ValueString()
+		})
+
+		data.PlacementPools = append(data.PlacementPools, ZonePlacementPoolModel{
+			Name:           types.StringValue(pp.Key),
+			IndexPool:      types.StringValue(pp.Val.IndexPool),
+			DataExtraPool:  types.StringValue(pp.Val.DataExtraPool),
+			StorageClasses: storageClasses,
+		})
+	}
+	sort.Slice(data.PlacementPools, func(i, j int) bool {
+		return data.PlacementPools[i].Name.ValueString() < data.PlacementPools[j].Name.ValueString()
+	})
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}